@@ -0,0 +1,163 @@
+package captcha
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/weplanx/go/help"
+	"math"
+	"math/rand"
+	"time"
+)
+
+//go:generate go run sounds/gen.go
+
+//go:embed sounds/*.pcm
+var sounds embed.FS
+
+// voices are the spoken digits 0-9 rendered by sounds/gen.go, 16 kHz unsigned 8-bit mono PCM.
+var voices = func() (v [10][]float64) {
+	for i := range v {
+		b, _ := sounds.ReadFile(fmt.Sprintf(`sounds/%d.pcm`, i))
+		v[i] = make([]float64, len(b))
+		for j, s := range b {
+			v[i][j] = float64(int(s)-128) / 127
+		}
+	}
+	return
+}()
+
+const sampleRate = 16000
+
+// Audio speaks the digits as WAV over background noise, the accessible alternative to Image.
+type Audio struct {
+	Length int
+	// Noise is the volume of the background noise, from 0 to 1.
+	Noise float64
+}
+
+type AudioOption func(x *Audio)
+
+func NewAudio(options ...AudioOption) *Audio {
+	x := &Audio{
+		Length: 6,
+		Noise:  0.3,
+	}
+	for _, v := range options {
+		v(x)
+	}
+	return x
+}
+
+func SetAudioLength(v int) AudioOption {
+	return func(x *Audio) {
+		x.Length = v
+	}
+}
+
+func SetAudioNoise(v float64) AudioOption {
+	return func(x *Audio) {
+		x.Noise = v
+	}
+}
+
+var (
+	ErrAudioNotDigit = errors.New("the audio captcha only speaks digits")
+)
+
+// CreateAudio generates the digits, saves them by Create and renders them.
+func (x *Captcha) CreateAudio(ctx context.Context, audio *Audio, ttl time.Duration) (c *Challenge, err error) {
+	var code string
+	if code, err = randomCode(audio.Length, "0123456789"); err != nil {
+		return
+	}
+	var data []byte
+	if data, err = audio.Render(code); err != nil {
+		return
+	}
+	c = &Challenge{Id: help.Uuid(), Data: data, MIME: "audio/wav"}
+	if err = x.Create(ctx, c.Id, code, ttl); err != nil {
+		return
+	}
+	return
+}
+
+// Render speaks the code with random pauses, speed and volume, over white noise and babble.
+func (x *Audio) Render(code string) (_ []byte, err error) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var speech [][]float64
+	size := r.Intn(sampleRate/4) + sampleRate/4
+	for _, v := range code {
+		if v < '0' || v > '9' {
+			return nil, ErrAudioNotDigit
+		}
+		s := resample(voices[v-'0'], 0.9+r.Float64()*0.2)
+		speech = append(speech, s)
+		size += len(s) + sampleRate/4 + r.Intn(sampleRate/4)
+	}
+	size += sampleRate / 4
+
+	track := make([]float64, size)
+	for i := range track {
+		track[i] = (r.Float64()*2 - 1) * x.Noise * 0.2
+	}
+	// babble of reversed and shifted digits, spoken all along the track
+	for offset := 0; offset < size; offset += sampleRate / 5 {
+		s := resample(voices[r.Intn(10)], 0.7+r.Float64()*0.6)
+		gain := x.Noise * (0.2 + r.Float64()*0.3)
+		for i := range s {
+			if offset+i < size {
+				track[offset+i] += s[len(s)-1-i] * gain
+			}
+		}
+	}
+	offset := r.Intn(sampleRate/4) + sampleRate/4
+	for _, s := range speech {
+		gain := 0.8 + r.Float64()*0.2
+		for i, v := range s {
+			track[offset+i] += v * gain
+		}
+		offset += len(s) + sampleRate/4 + r.Intn(sampleRate/4)
+	}
+
+	pcm := make([]byte, size)
+	for i, v := range track {
+		pcm[i] = uint8(128 + math.Round(math.Max(-1, math.Min(1, v))*127))
+	}
+	return wav(pcm)
+}
+
+// resample changes the speed and the pitch together by linear interpolation.
+func resample(s []float64, speed float64) []float64 {
+	n := int(float64(len(s)) / speed)
+	v := make([]float64, n)
+	for i := range v {
+		p := float64(i) * speed
+		j := int(p)
+		if j+1 >= len(s) {
+			v[i] = s[len(s)-1]
+			continue
+		}
+		v[i] = s[j] + (s[j+1]-s[j])*(p-float64(j))
+	}
+	return v
+}
+
+func wav(pcm []byte) (_ []byte, err error) {
+	var buf bytes.Buffer
+	header := []interface{}{
+		[]byte("RIFF"), uint32(36 + len(pcm)), []byte("WAVE"),
+		[]byte("fmt "), uint32(16), uint16(1), uint16(1), uint32(sampleRate), uint32(sampleRate), uint16(1), uint16(8),
+		[]byte("data"), uint32(len(pcm)),
+	}
+	for _, v := range header {
+		if err = binary.Write(&buf, binary.LittleEndian, v); err != nil {
+			return
+		}
+	}
+	buf.Write(pcm)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,48 @@
+package captcha_test
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/captcha"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateAudio(t *testing.T) {
+	ctx := context.TODO()
+	c, err := x.CreateAudio(ctx, captcha.NewAudio(), time.Second*60)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, c.Id)
+	assert.Equal(t, "audio/wav", c.MIME)
+	assert.True(t, strings.HasPrefix(c.DataURI(), "data:audio/wav;base64,"))
+
+	assert.Equal(t, "RIFF", string(c.Data[0:4]))
+	assert.Equal(t, "WAVE", string(c.Data[8:12]))
+	assert.Equal(t, uint32(len(c.Data)-8), binary.LittleEndian.Uint32(c.Data[4:8]))
+	assert.Equal(t, uint32(16000), binary.LittleEndian.Uint32(c.Data[24:28]))
+	assert.Equal(t, uint32(len(c.Data)-44), binary.LittleEndian.Uint32(c.Data[40:44]))
+
+	code, _ := x.Store.Get(ctx, x.Key(c.Id))
+	assert.Len(t, code, 6)
+	assert.Equal(t, "", strings.Trim(code, "0123456789"))
+	assert.ErrorIs(t, x.Verify(ctx, c.Id, "abcdef"), captcha.ErrCaptchaInconsistent)
+	assert.NoError(t, x.Verify(ctx, c.Id, code))
+}
+
+func TestAudioRender(t *testing.T) {
+	audio := captcha.NewAudio(
+		captcha.SetAudioLength(4),
+		captcha.SetAudioNoise(0),
+	)
+	assert.Equal(t, 4, audio.Length)
+	short, err := audio.Render("1234")
+	assert.NoError(t, err)
+	long, err := audio.Render("12345678")
+	assert.NoError(t, err)
+	assert.Greater(t, len(long), len(short))
+
+	_, err = audio.Render("12a4")
+	assert.ErrorIs(t, err, captcha.ErrAudioNotDigit)
+}
@@ -2,48 +2,128 @@ package captcha
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	errx "errors"
 	"fmt"
 	"github.com/cloudwego/hertz/pkg/common/errors"
-	"github.com/redis/go-redis/v9"
+	"github.com/weplanx/go/store"
+	"math/big"
 	"time"
 )
 
 type Captcha struct {
-	RDb *redis.Client
+	Store store.Store
 }
 
-func New(rdb *redis.Client) *Captcha {
-	return &Captcha{RDb: rdb}
+func New(s store.Store) *Captcha {
+	return &Captcha{Store: s}
 }
 
 func (x *Captcha) Key(name string) string {
 	return fmt.Sprintf(`captcha:%s`, name)
 }
 
-func (x *Captcha) Create(ctx context.Context, name string, code string, ttl time.Duration) string {
-	return x.RDb.Set(ctx, x.Key(name), code, ttl).Val()
+// AttemptsKey is in the cluster slot of Key, tagged by it.
+func (x *Captcha) AttemptsKey(name string) string {
+	return fmt.Sprintf(`{%s}:attempts`, x.Key(name))
+}
+
+func (x *Captcha) Create(ctx context.Context, name string, code string, ttl time.Duration) error {
+	return x.Store.Set(ctx, x.Key(name), code, ttl)
 }
 
 func (x *Captcha) Exists(ctx context.Context, name string) bool {
-	return x.RDb.Exists(ctx, x.Key(name)).Val() != 0
+	ok, _ := x.Store.Exists(ctx, x.Key(name))
+	return ok
 }
 
 var (
 	ErrCaptchaNotExists    = errors.NewPublic("the captcha does not exists")
 	ErrCaptchaInconsistent = errors.NewPublic("tha captcha is invalid")
+	ErrCaptchaExhausted    = errors.NewPublic("the captcha attempts are exhausted")
 )
 
 func (x *Captcha) Verify(ctx context.Context, name string, code string) error {
-	if !x.Exists(ctx, name) {
+	result, err := x.Store.Get(ctx, x.Key(name))
+	if err != nil {
 		return ErrCaptchaNotExists
 	}
-	result := x.RDb.Get(ctx, x.Key(name)).Val()
-	if result != code {
+	if !equal(result, code) {
 		return ErrCaptchaInconsistent
 	}
 	return nil
 }
 
+// Consume verifies the code only once, it is deleted on success,
+// and after the number of failed attempts, so that a short code cannot be brute forced.
+// The attempt is counted before the code is compared, parallel guesses cannot exceed attempts.
+func (x *Captcha) Consume(ctx context.Context, name string, code string, attempts int64) (err error) {
+	key := x.Key(name)
+	var result string
+	var n int64
+	if result, n, err = x.Store.Attempt(ctx, key, x.AttemptsKey(name), attempts); err != nil {
+		if errx.Is(err, store.ErrNotExists) {
+			return ErrCaptchaNotExists
+		}
+		return
+	}
+	if !equal(result, code) {
+		if n >= attempts {
+			return ErrCaptchaExhausted
+		}
+		return ErrCaptchaInconsistent
+	}
+	// the last attempt has deleted them
+	if n >= attempts {
+		return
+	}
+	var ok bool
+	if ok, err = x.Store.CompareAndDelete(ctx, key, result); err != nil {
+		return
+	}
+	// consumed by a concurrent request
+	if !ok {
+		return ErrCaptchaNotExists
+	}
+	_, err = x.Store.Del(ctx, x.AttemptsKey(name))
+	return
+}
+
 func (x *Captcha) Delete(ctx context.Context, name string) int64 {
-	return x.RDb.Del(ctx, x.Key(name)).Val()
+	n, _ := x.Store.Del(ctx, x.Key(name), x.AttemptsKey(name))
+	return n
+}
+
+func equal(a string, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// Challenge is a generated captcha, the answer is saved under Id by Create.
+type Challenge struct {
+	Id   string
+	Data []byte
+	MIME string
+}
+
+func (x *Challenge) Base64() string {
+	return base64.StdEncoding.EncodeToString(x.Data)
+}
+
+func (x *Challenge) DataURI() string {
+	return fmt.Sprintf(`data:%s;base64,%s`, x.MIME, x.Base64())
+}
+
+func randomCode(n int, charset string) (_ string, err error) {
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(charset)))
+	for i := range b {
+		var v *big.Int
+		if v, err = rand.Int(rand.Reader, max); err != nil {
+			return
+		}
+		b[i] = charset[v.Int64()]
+	}
+	return string(b), nil
 }
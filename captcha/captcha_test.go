@@ -2,11 +2,16 @@ package captcha_test
 
 import (
 	"context"
+	errx "errors"
+	"fmt"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/weplanx/go/captcha"
+	"github.com/weplanx/go/store"
 	"log"
 	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -18,16 +23,16 @@ func TestMain(m *testing.M) {
 	if err != nil {
 		log.Fatalln(err)
 	}
-	x = captcha.New(redis.NewClient(opts))
+	x = captcha.New(store.NewRedis(redis.NewClient(opts)))
 	os.Exit(m.Run())
 }
 
 func TestCreate(t *testing.T) {
 	ctx := context.TODO()
-	status := x.Create(ctx, "dev1", "abcd", time.Second*60)
-	assert.Equal(t, "OK", status)
-	status = x.Create(ctx, "dev2", "abcd", time.Millisecond)
-	assert.Equal(t, "OK", status)
+	err := x.Create(ctx, "dev1", "abcd", time.Second*60)
+	assert.NoError(t, err)
+	err = x.Create(ctx, "dev2", "abcd", time.Millisecond)
+	assert.NoError(t, err)
 }
 
 func TestVerify(t *testing.T) {
@@ -49,3 +54,88 @@ func TestDelete(t *testing.T) {
 	exists = x.Exists(context.TODO(), "dev1")
 	assert.False(t, exists)
 }
+
+func TestConsume(t *testing.T) {
+	ctx := context.TODO()
+	x.Create(ctx, "sms1", "1234", time.Second*60)
+	assert.ErrorIs(t, x.Consume(ctx, "sms1", "0000", 3), captcha.ErrCaptchaInconsistent)
+	n, _ := x.Store.Get(ctx, x.AttemptsKey("sms1"))
+	assert.Equal(t, "1", n)
+	assert.NoError(t, x.Consume(ctx, "sms1", "1234", 3))
+	assert.False(t, x.Exists(ctx, "sms1"))
+	exists, _ := x.Store.Exists(ctx, x.AttemptsKey("sms1"))
+	assert.False(t, exists)
+	assert.ErrorIs(t, x.Consume(ctx, "sms1", "1234", 3), captcha.ErrCaptchaNotExists)
+}
+
+func TestConsumeExhausted(t *testing.T) {
+	ctx := context.TODO()
+	x.Create(ctx, "sms2", "1234", time.Second*60)
+	assert.ErrorIs(t, x.Consume(ctx, "sms2", "0000", 3), captcha.ErrCaptchaInconsistent)
+	assert.ErrorIs(t, x.Consume(ctx, "sms2", "1111", 3), captcha.ErrCaptchaInconsistent)
+	ttl, _ := x.Store.TTL(ctx, x.AttemptsKey("sms2"))
+	assert.Greater(t, ttl, time.Second*50)
+	assert.ErrorIs(t, x.Consume(ctx, "sms2", "2222", 3), captcha.ErrCaptchaExhausted)
+	assert.False(t, x.Exists(ctx, "sms2"))
+	assert.ErrorIs(t, x.Consume(ctx, "sms2", "1234", 3), captcha.ErrCaptchaNotExists)
+}
+
+func TestConsumeConcurrent(t *testing.T) {
+	ctx := context.TODO()
+	x.Create(ctx, "sms3", "1234", time.Second*60)
+	results := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			results <- x.Consume(ctx, "sms3", "1234", 3)
+		}()
+	}
+	success := 0
+	for i := 0; i < 10; i++ {
+		if err := <-results; err == nil {
+			success++
+		} else if !errx.Is(err, captcha.ErrCaptchaExhausted) {
+			assert.ErrorIs(t, err, captcha.ErrCaptchaNotExists)
+		}
+	}
+	assert.Equal(t, 1, success)
+}
+
+// slow delays the calls to the store, widening the windows between them.
+type slow struct {
+	store.Store
+}
+
+func (x slow) Attempt(ctx context.Context, key string, counter string, max int64) (string, int64, error) {
+	time.Sleep(time.Millisecond * 5)
+	return x.Store.Attempt(ctx, key, counter, max)
+}
+
+func (x slow) CompareAndDelete(ctx context.Context, key string, value string) (bool, error) {
+	time.Sleep(time.Millisecond * 5)
+	return x.Store.CompareAndDelete(ctx, key, value)
+}
+
+func TestConsumeConcurrentGuesses(t *testing.T) {
+	ctx := context.TODO()
+	for _, s := range []store.Store{x.Store, slow{store.NewMemory()}} {
+		c := captcha.New(s)
+		c.Create(ctx, "sms4", "abcd", time.Second*60)
+		var wg sync.WaitGroup
+		var compared atomic.Int64
+		for i := 0; i < 1000; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := c.Consume(ctx, "sms4", fmt.Sprintf("%04d", i), 3)
+				assert.Error(t, err)
+				if errx.Is(err, captcha.ErrCaptchaInconsistent) {
+					compared.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		// the third attempt is answered by ErrCaptchaExhausted
+		assert.LessOrEqual(t, compared.Load(), int64(2))
+		assert.False(t, c.Exists(ctx, "sms4"))
+	}
+}
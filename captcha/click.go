@@ -0,0 +1,176 @@
+package captcha
+
+import (
+	"context"
+	"fmt"
+	"github.com/weplanx/go/help"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font/opentype"
+	"image"
+	"image/color"
+	"time"
+)
+
+// Click scatters characters over the background, the user clicks those of the prompt in order.
+type Click struct {
+	Width  int
+	Height int
+	// Count is the length of the prompt, Decoys are the other characters drawn.
+	Count   int
+	Decoys  int
+	Charset string
+	// Size is the box of each character.
+	Size int
+	// Tolerance is the accepted distance in pixels to the center of a character.
+	Tolerance   int
+	Fonts       []*opentype.Font
+	Backgrounds []image.Image
+}
+
+type ClickOption func(x *Click)
+
+func NewClick(options ...ClickOption) *Click {
+	x := &Click{
+		Width:     300,
+		Height:    150,
+		Count:     3,
+		Decoys:    2,
+		Charset:   "ABCDEFGHJKMNPQRSTUVWXYZ23456789",
+		Size:      44,
+		Tolerance: 20,
+		Fonts:     defaultFonts(),
+	}
+	for _, v := range options {
+		v(x)
+	}
+	return x
+}
+
+func SetClickSize(width int, height int) ClickOption {
+	return func(x *Click) {
+		x.Width = width
+		x.Height = height
+	}
+}
+
+func SetClickCount(count int, decoys int) ClickOption {
+	return func(x *Click) {
+		x.Count = count
+		x.Decoys = decoys
+	}
+}
+
+func SetClickCharset(v string) ClickOption {
+	return func(x *Click) {
+		x.Charset = v
+	}
+}
+
+func SetClickTolerance(v int) ClickOption {
+	return func(x *Click) {
+		x.Tolerance = v
+	}
+}
+
+func SetClickBackgrounds(v ...image.Image) ClickOption {
+	return func(x *Click) {
+		x.Backgrounds = v
+	}
+}
+
+type ClickChallenge struct {
+	Id string `json:"id"`
+	// Image is PNG.
+	Image []byte `json:"image"`
+	// Prompt are the characters to click in order.
+	Prompt string `json:"prompt"`
+}
+
+// CreateClick saves the centers of the prompt characters by Create.
+func (x *Captcha) CreateClick(ctx context.Context, c *Click, ttl time.Duration) (_ *ClickChallenge, err error) {
+	r := newRand()
+	chars := []rune(c.Charset)
+	n := c.Count + c.Decoys
+	cols, rows := c.Width/c.Size, c.Height/c.Size
+	if n > len(chars) || n > cols*rows {
+		return nil, fmt.Errorf("the click captcha cannot fit %d characters", n)
+	}
+	r.Shuffle(len(chars), func(i, j int) {
+		chars[i], chars[j] = chars[j], chars[i]
+	})
+
+	bg := scene(r, c.Width, c.Height, c.Backgrounds)
+	// lighten the background for the dark glyphs
+	for i := range bg.Pix {
+		if i%4 != 3 {
+			bg.Pix[i] = uint8((int(bg.Pix[i]) + 255*2) / 3)
+		}
+	}
+	glyphs := &Image{Height: c.Size, Fonts: c.Fonts}
+	cellW, cellH := c.Width/cols, c.Height/rows
+	answer := make([]Point, c.Count)
+	for i, cell := range r.Perm(cols * rows)[:n] {
+		var glyph *image.RGBA
+		if glyph, err = glyphs.glyph(r, chars[i]); err != nil {
+			return
+		}
+		at := image.Pt(
+			cell%cols*cellW+r.Intn(cellW-c.Size+1),
+			cell/cols*cellH+r.Intn(cellH-c.Size+1),
+		)
+		outline(glyph)
+		draw.Draw(bg, glyph.Bounds().Add(at), glyph, image.Point{}, draw.Over)
+		if i < c.Count {
+			answer[i] = Point{X: at.X + c.Size/2, Y: at.Y + c.Size/2}
+		}
+	}
+
+	challenge := &ClickChallenge{Id: help.Uuid(), Prompt: string(chars[:c.Count])}
+	if challenge.Image, err = encode(bg); err != nil {
+		return
+	}
+	if err = x.save(ctx, challenge.Id, answer, ttl); err != nil {
+		return
+	}
+	return challenge, nil
+}
+
+// outline surrounds the glyph with white, to stand out from the background.
+func outline(glyph *image.RGBA) {
+	b := glyph.Bounds()
+	src := image.NewRGBA(b)
+	copy(src.Pix, glyph.Pix)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if src.RGBAAt(x, y).A != 0 {
+				continue
+			}
+			for _, d := range []image.Point{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+				p := image.Pt(x, y).Add(d)
+				if p.In(b) && src.RGBAAt(p.X, p.Y).A > 128 {
+					glyph.SetRGBA(x, y, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+					break
+				}
+			}
+		}
+	}
+}
+
+// VerifyClick checks the clicked points against the prompt characters in order.
+// The challenge is consumed by the first attempt.
+func (x *Captcha) VerifyClick(ctx context.Context, c *Click, id string, points []Point) (err error) {
+	var answer []Point
+	if answer, err = x.redeem(ctx, id); err != nil {
+		return
+	}
+	if len(points) != len(answer) {
+		return ErrCaptchaInconsistent
+	}
+	for i, v := range answer {
+		dx, dy := points[i].X-v.X, points[i].Y-v.Y
+		if dx*dx+dy*dy > c.Tolerance*c.Tolerance {
+			return ErrCaptchaInconsistent
+		}
+	}
+	return
+}
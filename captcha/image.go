@@ -0,0 +1,275 @@
+package captcha
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/weplanx/go/help"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"math/rand"
+	"time"
+)
+
+var errNoFonts = errors.New("the captcha has no fonts to render")
+
+// Image renders distorted characters as PNG, the fonts are embedded from golang.org/x/image.
+type Image struct {
+	Width   int
+	Height  int
+	Length  int
+	Charset string
+	// Noise is the number of noise curves, dots scale along with it.
+	Noise int
+	Fonts []*opentype.Font
+}
+
+type ImageOption func(x *Image)
+
+func NewImage(options ...ImageOption) *Image {
+	x := &Image{
+		Width:   160,
+		Height:  60,
+		Length:  4,
+		Charset: "ABCDEFGHJKMNPQRSTUVWXYZ23456789",
+		Noise:   4,
+		Fonts:   defaultFonts(),
+	}
+	for _, v := range options {
+		v(x)
+	}
+	return x
+}
+
+func defaultFonts() (fonts []*opentype.Font) {
+	for _, ttf := range [][]byte{goregular.TTF, gobold.TTF, gomonobold.TTF} {
+		f, _ := opentype.Parse(ttf)
+		fonts = append(fonts, f)
+	}
+	return
+}
+
+func SetSize(width int, height int) ImageOption {
+	return func(x *Image) {
+		x.Width = width
+		x.Height = height
+	}
+}
+
+func SetLength(v int) ImageOption {
+	return func(x *Image) {
+		x.Length = v
+	}
+}
+
+func SetCharset(v string) ImageOption {
+	return func(x *Image) {
+		x.Charset = v
+	}
+}
+
+func SetNoise(v int) ImageOption {
+	return func(x *Image) {
+		x.Noise = v
+	}
+}
+
+// SetFonts replaces the embedded fonts with TrueType or OpenType data,
+// the data that does not parse is skipped and the embedded fonts are kept when none parses.
+func SetFonts(v ...[]byte) ImageOption {
+	return func(x *Image) {
+		var fonts []*opentype.Font
+		for _, b := range v {
+			if f, err := opentype.Parse(b); err == nil {
+				fonts = append(fonts, f)
+			}
+		}
+		if len(fonts) != 0 {
+			x.Fonts = fonts
+		}
+	}
+}
+
+// CreateImage generates the code, saves it by Create and renders it.
+// The default charset is upper case, normalize the answer before Verify.
+func (x *Captcha) CreateImage(ctx context.Context, img *Image, ttl time.Duration) (c *Challenge, err error) {
+	var code string
+	if code, err = randomCode(img.Length, img.Charset); err != nil {
+		return
+	}
+	var data []byte
+	if data, err = img.Render(code); err != nil {
+		return
+	}
+	c = &Challenge{Id: help.Uuid(), Data: data, MIME: "image/png"}
+	if err = x.Create(ctx, c.Id, code, ttl); err != nil {
+		return
+	}
+	return
+}
+
+// Render draws the code, every call distorts differently.
+func (x *Image) Render(code string) (_ []byte, err error) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	canvas := image.NewRGBA(image.Rect(0, 0, x.Width, x.Height))
+	bg := color.RGBA{R: uint8(230 + r.Intn(26)), G: uint8(230 + r.Intn(26)), B: uint8(230 + r.Intn(26)), A: 255}
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+
+	runes := []rune(code)
+	step := float64(x.Width) / float64(len(runes)+1)
+	for i, v := range runes {
+		var glyph *image.RGBA
+		if glyph, err = x.glyph(r, v); err != nil {
+			return
+		}
+		cx := int(step*float64(i+1)) + r.Intn(int(step/4)+1) - int(step/8)
+		cy := x.Height/2 + r.Intn(x.Height/8+1) - x.Height/16
+		rect := glyph.Bounds().Add(image.Pt(cx-glyph.Bounds().Dx()/2, cy-glyph.Bounds().Dy()/2))
+		draw.Draw(canvas, rect, glyph, image.Point{}, draw.Over)
+	}
+
+	for i := 0; i < x.Noise; i++ {
+		curve(canvas, r, randomColor(r, 160))
+	}
+	for i := 0; i < x.Noise*x.Width*x.Height/400; i++ {
+		canvas.Set(r.Intn(x.Width), r.Intn(x.Height), randomColor(r, 200))
+	}
+
+	var buf bytes.Buffer
+	if err = png.Encode(&buf, wave(canvas, r)); err != nil {
+		return
+	}
+	return buf.Bytes(), nil
+}
+
+// glyph renders one character in a random font, size and color, then rotates it.
+func (x *Image) glyph(r *rand.Rand, v rune) (_ *image.RGBA, err error) {
+	if len(x.Fonts) == 0 {
+		return nil, errNoFonts
+	}
+	var face font.Face
+	if face, err = opentype.NewFace(x.Fonts[r.Intn(len(x.Fonts))], &opentype.FaceOptions{
+		Size:    float64(x.Height) * (0.55 + r.Float64()*0.2),
+		DPI:     72,
+		Hinting: font.HintingFull,
+	}); err != nil {
+		return
+	}
+	defer face.Close()
+
+	size := x.Height
+	src := image.NewRGBA(image.Rect(0, 0, size, size))
+	d := &font.Drawer{
+		Dst:  src,
+		Src:  image.NewUniform(randomColor(r, 120)),
+		Face: face,
+	}
+	advance := d.MeasureString(string(v))
+	metrics := face.Metrics()
+	d.Dot = fixed.Point26_6{
+		X: (fixed.I(size) - advance) / 2,
+		Y: (fixed.I(size) + metrics.Ascent - metrics.Descent) / 2,
+	}
+	d.DrawString(string(v))
+	return rotate(src, (r.Float64()-0.5)*math.Pi/3), nil
+}
+
+func rotate(src *image.RGBA, angle float64) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(b)
+	cx, cy := float64(b.Dx())/2, float64(b.Dy())/2
+	sin, cos := math.Sincos(angle)
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			dx, dy := float64(x)-cx, float64(y)-cy
+			sx := int(cos*dx + sin*dy + cx)
+			sy := int(-sin*dx + cos*dy + cy)
+			if sx >= 0 && sx < b.Dx() && sy >= 0 && sy < b.Dy() {
+				dst.SetRGBA(x, y, src.RGBAAt(sx, sy))
+			}
+		}
+	}
+	return dst
+}
+
+// wave shifts rows and columns along sine waves.
+func wave(src *image.RGBA, r *rand.Rand) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(b)
+	amplitude := float64(b.Dy()) / 20 * (1 + r.Float64())
+	period := float64(b.Dx()) / (1.5 + r.Float64())
+	phase := r.Float64() * 2 * math.Pi
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			sx := x + int(amplitude*math.Sin(2*math.Pi*float64(y)/period+phase))
+			sy := y + int(amplitude*math.Sin(2*math.Pi*float64(x)/period+phase))
+			if sx < 0 || sx >= b.Dx() || sy < 0 || sy >= b.Dy() {
+				dst.SetRGBA(x, y, src.RGBAAt(x, y))
+				continue
+			}
+			dst.SetRGBA(x, y, src.RGBAAt(sx, sy))
+		}
+	}
+	return dst
+}
+
+func curve(img *image.RGBA, r *rand.Rand, c color.RGBA) {
+	b := img.Bounds()
+	amplitude := float64(b.Dy()) / 4 * r.Float64()
+	period := float64(b.Dx()) * (0.5 + r.Float64())
+	phase := r.Float64() * 2 * math.Pi
+	base := float64(r.Intn(b.Dy()))
+	thickness := 1 + r.Intn(2)
+	for x := 0; x < b.Dx(); x++ {
+		y := int(base + amplitude*math.Sin(2*math.Pi*float64(x)/period+phase))
+		for t := 0; t < thickness; t++ {
+			img.SetRGBA(x, y+t, c)
+		}
+	}
+}
+
+// background is a gradient with translucent circles, textured enough for the slider piece.
+func background(r *rand.Rand, width int, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	from, to := randomColor(r, 256), randomColor(r, 256)
+	for y := 0; y < height; y++ {
+		k := float64(y) / float64(height)
+		c := color.RGBA{
+			R: uint8(float64(from.R)*(1-k) + float64(to.R)*k),
+			G: uint8(float64(from.G)*(1-k) + float64(to.G)*k),
+			B: uint8(float64(from.B)*(1-k) + float64(to.B)*k),
+			A: 255,
+		}
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	for i := 0; i < 16; i++ {
+		c := randomColor(r, 256)
+		cx, cy := r.Intn(width), r.Intn(height)
+		radius := height/10 + r.Intn(height/3+1)
+		for y := cy - radius; y < cy+radius; y++ {
+			for x := cx - radius; x < cx+radius; x++ {
+				if !image.Pt(x, y).In(img.Rect) || (x-cx)*(x-cx)+(y-cy)*(y-cy) > radius*radius {
+					continue
+				}
+				v := img.RGBAAt(x, y)
+				img.SetRGBA(x, y, color.RGBA{R: (v.R + c.R) / 2, G: (v.G + c.G) / 2, B: (v.B + c.B) / 2, A: 255})
+			}
+		}
+	}
+	return img
+}
+
+func randomColor(r *rand.Rand, max int) color.RGBA {
+	return color.RGBA{R: uint8(r.Intn(max)), G: uint8(r.Intn(max)), B: uint8(r.Intn(max)), A: 255}
+}
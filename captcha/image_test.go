@@ -0,0 +1,76 @@
+package captcha_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/captcha"
+	"golang.org/x/image/font/gofont/goitalic"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateImage(t *testing.T) {
+	ctx := context.TODO()
+	img := captcha.NewImage()
+	c, err := x.CreateImage(ctx, img, time.Second*60)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, c.Id)
+	assert.Equal(t, "image/png", c.MIME)
+	assert.True(t, strings.HasPrefix(c.DataURI(), "data:image/png;base64,"))
+
+	decoded, err := png.Decode(bytes.NewReader(c.Data))
+	assert.NoError(t, err)
+	assert.Equal(t, 160, decoded.Bounds().Dx())
+	assert.Equal(t, 60, decoded.Bounds().Dy())
+
+	code, _ := x.Store.Get(ctx, x.Key(c.Id))
+	assert.Len(t, code, 4)
+	assert.ErrorIs(t, x.Verify(ctx, c.Id, "0000"), captcha.ErrCaptchaInconsistent)
+	assert.NoError(t, x.Verify(ctx, c.Id, code))
+}
+
+func TestImageOptions(t *testing.T) {
+	img := captcha.NewImage(
+		captcha.SetSize(240, 80),
+		captcha.SetLength(6),
+		captcha.SetCharset("0123456789"),
+		captcha.SetNoise(8),
+		captcha.SetFonts(goitalic.TTF),
+	)
+	assert.Len(t, img.Fonts, 1)
+	data, err := img.Render("123456")
+	assert.NoError(t, err)
+	decoded, err := png.Decode(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, 240, decoded.Bounds().Dx())
+	assert.Equal(t, 80, decoded.Bounds().Dy())
+
+	other, err := img.Render("123456")
+	assert.NoError(t, err)
+	assert.NotEqual(t, data, other)
+
+	c, err := x.CreateImage(context.TODO(), img, time.Second*60)
+	assert.NoError(t, err)
+	code, _ := x.Store.Get(context.TODO(), x.Key(c.Id))
+	assert.Len(t, code, 6)
+	assert.Equal(t, "", strings.Trim(code, "0123456789"))
+}
+
+func TestImageFonts(t *testing.T) {
+	img := captcha.NewImage(captcha.SetFonts([]byte("x")))
+	assert.NotEmpty(t, img.Fonts)
+	c, err := x.CreateImage(context.TODO(), img, time.Second*60)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, c.Data)
+
+	img.Fonts = nil
+	_, err = img.Render("1234")
+	assert.Error(t, err)
+	_, err = x.CreateClick(context.TODO(), captcha.NewClick(func(x *captcha.Click) {
+		x.Fonts = nil
+	}), time.Second*60)
+	assert.Error(t, err)
+}
@@ -0,0 +1,178 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	errx "errors"
+	"fmt"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/errors"
+	"github.com/weplanx/go/locker"
+)
+
+// Extractor reads a value from the request, empty when absent.
+type Extractor func(c *app.RequestContext) string
+
+func FromHeader(name string) Extractor {
+	return func(c *app.RequestContext) string {
+		return string(c.GetHeader(name))
+	}
+}
+
+func FromForm(name string) Extractor {
+	return func(c *app.RequestContext) string {
+		return c.PostForm(name)
+	}
+}
+
+func FromQuery(name string) Extractor {
+	return func(c *app.RequestContext) string {
+		return c.Query(name)
+	}
+}
+
+// FromJSON reads a top level field of a JSON body, the body is left for the handler.
+func FromJSON(name string) Extractor {
+	return func(c *app.RequestContext) string {
+		var body map[string]interface{}
+		if err := json.Unmarshal(c.Request.Body(), &body); err != nil {
+			return ""
+		}
+		switch v := body[name].(type) {
+		case string:
+			return v
+		case float64:
+			return fmt.Sprint(v)
+		}
+		return ""
+	}
+}
+
+// Guard configures the middleware of Captcha.Guard.
+type Guard struct {
+	Id     []Extractor
+	Answer []Extractor
+	// Attempts are the failures allowed before the captcha is deleted.
+	Attempts int64
+	// Verify is Consume by default, replace it for other challenges, e.g. VerifySlider.
+	Verify func(ctx context.Context, id string, answer string) error
+	// Locker makes the guard adaptive, the captcha is only required
+	// when one of Names has reached Threshold failures.
+	Locker    *locker.Locker
+	Threshold int64
+	Names     func(ctx context.Context, c *app.RequestContext) []string
+}
+
+type GuardOption func(x *Guard)
+
+// SetIdExtractors defaults to the X-Captcha-Id header, then the captcha_id form and JSON fields.
+func SetIdExtractors(v ...Extractor) GuardOption {
+	return func(x *Guard) {
+		x.Id = v
+	}
+}
+
+// SetAnswerExtractors defaults to the X-Captcha header, then the captcha form and JSON fields.
+func SetAnswerExtractors(v ...Extractor) GuardOption {
+	return func(x *Guard) {
+		x.Answer = v
+	}
+}
+
+func SetAttempts(v int64) GuardOption {
+	return func(x *Guard) {
+		x.Attempts = v
+	}
+}
+
+func SetVerify(v func(ctx context.Context, id string, answer string) error) GuardOption {
+	return func(x *Guard) {
+		x.Verify = v
+	}
+}
+
+// SetAdaptive requires the captcha once a locker name of the request, the client IP when names is nil,
+// counts threshold failures. The handler counts the failures by locker.Update.
+func SetAdaptive(l *locker.Locker, threshold int64, names func(ctx context.Context, c *app.RequestContext) []string) GuardOption {
+	return func(x *Guard) {
+		x.Locker = l
+		x.Threshold = threshold
+		x.Names = names
+	}
+}
+
+var (
+	ErrCaptchaRequired = errors.NewPublic("the captcha is required")
+)
+
+// Guard verifies and consumes the captcha of the request, aborting with a public error on failure.
+func (x *Captcha) Guard(options ...GuardOption) app.HandlerFunc {
+	g := &Guard{
+		Id:       []Extractor{FromHeader("X-Captcha-Id"), FromForm("captcha_id"), FromJSON("captcha_id")},
+		Answer:   []Extractor{FromHeader("X-Captcha"), FromForm("captcha"), FromJSON("captcha")},
+		Attempts: 3,
+	}
+	for _, v := range options {
+		v(g)
+	}
+	if g.Verify == nil {
+		g.Verify = func(ctx context.Context, id string, answer string) error {
+			return x.Consume(ctx, id, answer, g.Attempts)
+		}
+	}
+	return func(ctx context.Context, c *app.RequestContext) {
+		if g.Locker != nil {
+			required, err := g.required(ctx, c)
+			if err != nil {
+				c.Error(err)
+				c.Abort()
+				return
+			}
+			if !required {
+				c.Next(ctx)
+				return
+			}
+		}
+
+		id, answer := extract(c, g.Id), extract(c, g.Answer)
+		if id == "" || answer == "" {
+			c.Error(ErrCaptchaRequired)
+			c.Abort()
+			return
+		}
+		if err := g.Verify(ctx, id, answer); err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Next(ctx)
+	}
+}
+
+func (x *Guard) required(ctx context.Context, c *app.RequestContext) (_ bool, err error) {
+	names := []string{c.ClientIP()}
+	if x.Names != nil {
+		names = x.Names(ctx, c)
+	}
+	for _, name := range names {
+		if err = x.Locker.Verify(ctx, name, x.Threshold); err != nil {
+			switch {
+			case errx.Is(err, locker.ErrLocked):
+				return true, nil
+			case errx.Is(err, locker.ErrLockerNotExists):
+				continue
+			}
+			return
+		}
+	}
+	return false, nil
+}
+
+func extract(c *app.RequestContext, extractors []Extractor) string {
+	for _, v := range extractors {
+		if s := v(c); s != "" {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,123 @@
+package captcha_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/captcha"
+	"github.com/weplanx/go/help"
+	"github.com/weplanx/go/locker"
+	"github.com/weplanx/go/store"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newGuardEngine(guard app.HandlerFunc) *route.Engine {
+	engine := route.NewEngine(config.NewOptions([]config.Option{}))
+	engine.Use(help.ErrorHandler())
+	engine.POST("/login", guard, func(ctx context.Context, c *app.RequestContext) {
+		c.JSON(http.StatusOK, help.Ok())
+	})
+	return engine
+}
+
+func messageOf(t *testing.T, resp *ut.ResponseRecorder) string {
+	var r help.R
+	assert.NoError(t, json.Unmarshal(resp.Result().Body(), &r))
+	return r.Message
+}
+
+func TestGuard(t *testing.T) {
+	ctx := context.TODO()
+	engine := newGuardEngine(x.Guard())
+
+	resp := ut.PerformRequest(engine, "POST", "/login", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, captcha.ErrCaptchaRequired.Error(), messageOf(t, resp))
+
+	assert.NoError(t, x.Create(ctx, "guard1", "abcd", time.Second*60))
+	resp = ut.PerformRequest(engine, "POST", "/login", nil,
+		ut.Header{Key: "X-Captcha-Id", Value: "guard1"},
+		ut.Header{Key: "X-Captcha", Value: "abce"},
+	)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, captcha.ErrCaptchaInconsistent.Error(), messageOf(t, resp))
+
+	resp = ut.PerformRequest(engine, "POST", "/login", nil,
+		ut.Header{Key: "X-Captcha-Id", Value: "guard1"},
+		ut.Header{Key: "X-Captcha", Value: "abcd"},
+	)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.False(t, x.Exists(ctx, "guard1"))
+}
+
+func TestGuardBody(t *testing.T) {
+	ctx := context.TODO()
+	engine := newGuardEngine(x.Guard())
+
+	assert.NoError(t, x.Create(ctx, "guard2", "abcd", time.Second*60))
+	resp := ut.PerformRequest(engine, "POST", "/login",
+		&ut.Body{Body: strings.NewReader(`{"username":"dev","captcha_id":"guard2","captcha":"abcd"}`), Len: -1},
+		ut.Header{Key: "Content-Type", Value: "application/json"},
+	)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	assert.NoError(t, x.Create(ctx, "guard3", "abcd", time.Second*60))
+	resp = ut.PerformRequest(engine, "POST", "/login",
+		&ut.Body{Body: strings.NewReader(`captcha_id=guard3&captcha=abcd`), Len: -1},
+		ut.Header{Key: "Content-Type", Value: "application/x-www-form-urlencoded"},
+	)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestGuardAdaptive(t *testing.T) {
+	ctx := context.TODO()
+	l := locker.New(store.NewMemory())
+	engine := newGuardEngine(x.Guard(
+		captcha.SetIdExtractors(captcha.FromQuery("id")),
+		captcha.SetAnswerExtractors(captcha.FromQuery("answer")),
+		captcha.SetAdaptive(l, 2, func(ctx context.Context, c *app.RequestContext) []string {
+			return []string{"ip:" + c.ClientIP(), "user:" + c.Query("username")}
+		}),
+	))
+
+	resp := ut.PerformRequest(engine, "POST", "/login?username=dev", nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	l.Update(ctx, "user:dev", time.Minute)
+	l.Update(ctx, "user:dev", time.Minute)
+	resp = ut.PerformRequest(engine, "POST", "/login?username=dev", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, captcha.ErrCaptchaRequired.Error(), messageOf(t, resp))
+	resp = ut.PerformRequest(engine, "POST", "/login?username=other", nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	assert.NoError(t, x.Create(ctx, "guard4", "abcd", time.Second*60))
+	resp = ut.PerformRequest(engine, "POST", "/login?username=dev&id=guard4&answer=abcd", nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestGuardVerify(t *testing.T) {
+	ctx := context.TODO()
+	w := captcha.NewWork([]byte("6ea3c1f8b2d94e0a"), captcha.SetDifficulty(8))
+	engine := newGuardEngine(x.Guard(
+		captcha.SetVerify(func(ctx context.Context, id string, answer string) error {
+			return x.RedeemWork(ctx, w, w.Difficulty, id, answer)
+		}),
+	))
+	p, err := w.Issue(w.Difficulty)
+	assert.NoError(t, err)
+	nonce, err := captcha.SolveWork(ctx, p)
+	assert.NoError(t, err)
+	header := []ut.Header{{Key: "X-Captcha-Id", Value: p.Challenge}, {Key: "X-Captcha", Value: nonce}}
+	resp := ut.PerformRequest(engine, "POST", "/login", nil, header...)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	resp = ut.PerformRequest(engine, "POST", "/login", nil, header...)
+	assert.Equal(t, captcha.ErrWorkRedeemed.Error(), messageOf(t, resp))
+}
@@ -0,0 +1,269 @@
+package captcha
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	errx "errors"
+	"fmt"
+	"github.com/weplanx/go/help"
+	"github.com/weplanx/go/store"
+	"golang.org/x/image/draw"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Point is a position on the challenge image, T is the milliseconds into the drag for trails.
+type Point struct {
+	X int   `json:"x"`
+	Y int   `json:"y"`
+	T int64 `json:"t,omitempty"`
+}
+
+// Slider cuts a piece out of the background, the user drags it back to the hole.
+type Slider struct {
+	Width  int
+	Height int
+	// Piece is the size of the cut out.
+	Piece int
+	// Tolerance is the accepted distance in pixels to the hole.
+	Tolerance int
+	// CheckTrail requires a human looking drag between MinDuration and MaxDuration.
+	CheckTrail  bool
+	MinDuration time.Duration
+	MaxDuration time.Duration
+	// Backgrounds are scaled to the size, generated when empty.
+	Backgrounds []image.Image
+}
+
+type SliderOption func(x *Slider)
+
+func NewSlider(options ...SliderOption) *Slider {
+	x := &Slider{
+		Width:       300,
+		Height:      150,
+		Piece:       50,
+		Tolerance:   5,
+		MinDuration: time.Millisecond * 300,
+		MaxDuration: time.Second * 10,
+	}
+	for _, v := range options {
+		v(x)
+	}
+	return x
+}
+
+func SetSliderSize(width int, height int, piece int) SliderOption {
+	return func(x *Slider) {
+		x.Width = width
+		x.Height = height
+		x.Piece = piece
+	}
+}
+
+func SetSliderTolerance(v int) SliderOption {
+	return func(x *Slider) {
+		x.Tolerance = v
+	}
+}
+
+func SetSliderTrail(min time.Duration, max time.Duration) SliderOption {
+	return func(x *Slider) {
+		x.CheckTrail = true
+		x.MinDuration = min
+		x.MaxDuration = max
+	}
+}
+
+func SetSliderBackgrounds(v ...image.Image) SliderOption {
+	return func(x *Slider) {
+		x.Backgrounds = v
+	}
+}
+
+type SliderChallenge struct {
+	Id string `json:"id"`
+	// Background is PNG with the hole shaded.
+	Background []byte `json:"background"`
+	// Piece is PNG of Piece x Piece, to be placed at Y and dragged along X.
+	Piece []byte `json:"piece"`
+	Y     int    `json:"y"`
+}
+
+// CreateSlider saves the X offset of the hole by Create.
+func (x *Captcha) CreateSlider(ctx context.Context, s *Slider, ttl time.Duration) (c *SliderChallenge, err error) {
+	// the hole keeps a margin of 10 and a piece away from the start
+	if s.Piece <= 0 || s.Width-s.Piece*2-19 <= 0 || s.Height-s.Piece-19 <= 0 {
+		return nil, fmt.Errorf("the slider captcha cannot fit a piece of %d in %dx%d", s.Piece, s.Width, s.Height)
+	}
+	r := newRand()
+	bg := scene(r, s.Width, s.Height, s.Backgrounds)
+	at := image.Pt(
+		s.Piece+10+r.Intn(s.Width-s.Piece*2-19),
+		10+r.Intn(s.Height-s.Piece-19),
+	)
+
+	piece := image.NewRGBA(image.Rect(0, 0, s.Piece, s.Piece))
+	for y := 0; y < s.Piece; y++ {
+		for x := 0; x < s.Piece; x++ {
+			if !s.inside(x, y) {
+				continue
+			}
+			v := bg.RGBAAt(at.X+x, at.Y+y)
+			if s.edge(x, y) {
+				v = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+			}
+			piece.SetRGBA(x, y, v)
+			shade := bg.RGBAAt(at.X+x, at.Y+y)
+			bg.SetRGBA(at.X+x, at.Y+y, color.RGBA{R: shade.R / 3, G: shade.G / 3, B: shade.B / 3, A: 255})
+		}
+	}
+
+	c = &SliderChallenge{Id: help.Uuid(), Y: at.Y}
+	if c.Background, err = encode(bg); err != nil {
+		return
+	}
+	if c.Piece, err = encode(piece); err != nil {
+		return
+	}
+	if err = x.save(ctx, c.Id, []Point{{X: at.X, Y: at.Y}}, ttl); err != nil {
+		return
+	}
+	return
+}
+
+// inside shapes the piece as a square with tabs on the top and the right.
+func (x *Slider) inside(px int, py int) bool {
+	t := x.Piece / 6
+	if px >= t && px < x.Piece-t && py >= t && py < x.Piece-t {
+		return true
+	}
+	in := func(cx, cy int) bool {
+		return (px-cx)*(px-cx)+(py-cy)*(py-cy) <= t*t
+	}
+	return in(x.Piece-t, x.Piece/2) || in(x.Piece/2, t)
+}
+
+func (x *Slider) edge(px int, py int) bool {
+	return !x.inside(px-1, py) || !x.inside(px+1, py) || !x.inside(px, py-1) || !x.inside(px, py+1)
+}
+
+// VerifySlider checks the X offset the piece is dropped at, and the trail of the drag when CheckTrail.
+// The challenge is consumed by the first attempt.
+func (x *Captcha) VerifySlider(ctx context.Context, s *Slider, id string, offset int, trail []Point) (err error) {
+	var answer []Point
+	if answer, err = x.redeem(ctx, id); err != nil {
+		return
+	}
+	if len(answer) != 1 || abs(offset-answer[0].X) > s.Tolerance {
+		return ErrCaptchaInconsistent
+	}
+	if s.CheckTrail && !s.human(trail, offset) {
+		return ErrCaptchaInconsistent
+	}
+	return
+}
+
+// human rejects trails that are too short, too fast or slow, out of order,
+// not ending at the offset, or moving at a constant speed like a script.
+func (x *Slider) human(trail []Point, offset int) bool {
+	n := len(trail)
+	if n < 3 || abs(trail[n-1].X-offset) > x.Tolerance {
+		return false
+	}
+	d := time.Duration(trail[n-1].T-trail[0].T) * time.Millisecond
+	if d < x.MinDuration || d > x.MaxDuration {
+		return false
+	}
+	var speeds []float64
+	for i := 1; i < n; i++ {
+		dt := trail[i].T - trail[i-1].T
+		if dt < 0 {
+			return false
+		}
+		if dt > 0 {
+			speeds = append(speeds, float64(trail[i].X-trail[i-1].X)/float64(dt))
+		}
+	}
+	if len(speeds) < 2 {
+		return false
+	}
+	mean := 0.0
+	for _, v := range speeds {
+		mean += v
+	}
+	mean /= float64(len(speeds))
+	variance := 0.0
+	for _, v := range speeds {
+		variance += (v - mean) * (v - mean)
+	}
+	return variance/float64(len(speeds)) > 1e-4
+}
+
+func (x *Captcha) save(ctx context.Context, id string, answer []Point, ttl time.Duration) (err error) {
+	var b []byte
+	if b, err = json.Marshal(answer); err != nil {
+		return
+	}
+	return x.Create(ctx, id, string(b), ttl)
+}
+
+// redeem takes the answer once, so that positions cannot be tried one by one.
+func (x *Captcha) redeem(ctx context.Context, id string) (answer []Point, err error) {
+	var v string
+	if v, err = x.Store.Get(ctx, x.Key(id)); err != nil {
+		if errx.Is(err, store.ErrNotExists) {
+			err = ErrCaptchaNotExists
+		}
+		return
+	}
+	var ok bool
+	if ok, err = x.Store.CompareAndDelete(ctx, x.Key(id), v); err != nil {
+		return
+	}
+	if !ok {
+		return nil, ErrCaptchaNotExists
+	}
+	if err = json.Unmarshal([]byte(v), &answer); err != nil {
+		return
+	}
+	return
+}
+
+// scene picks one of the backgrounds scaled to the size, or generates one.
+func scene(r *rand.Rand, width int, height int, backgrounds []image.Image) *image.RGBA {
+	if len(backgrounds) == 0 {
+		return background(r, width, height)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	src := backgrounds[r.Intn(len(backgrounds))]
+	draw.ApproxBiLinear.Scale(img, img.Rect, src, src.Bounds(), draw.Src, nil)
+	return img
+}
+
+func encode(img image.Image) (_ []byte, err error) {
+	var buf bytes.Buffer
+	if err = png.Encode(&buf, img); err != nil {
+		return
+	}
+	return buf.Bytes(), nil
+}
+
+// newRand seeds by crypto/rand, the positions are the answers and must not be predictable.
+func newRand() *rand.Rand {
+	var seed [8]byte
+	if _, err := crand.Read(seed[:]); err != nil {
+		return rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return rand.New(rand.NewSource(int64(binary.LittleEndian.Uint64(seed[:]))))
+}
+
+func abs(v int) int {
+	return int(math.Abs(float64(v)))
+}
@@ -0,0 +1,149 @@
+package captcha_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/captcha"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+)
+
+func answerOf(t *testing.T, id string) (answer []captcha.Point) {
+	v, err := x.Store.Get(context.TODO(), x.Key(id))
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal([]byte(v), &answer))
+	return
+}
+
+func TestCreateSlider(t *testing.T) {
+	ctx := context.TODO()
+	s := captcha.NewSlider()
+	c, err := x.CreateSlider(ctx, s, time.Second*60)
+	assert.NoError(t, err)
+
+	bg, err := png.Decode(bytes.NewReader(c.Background))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 300, 150), bg.Bounds())
+	piece, err := png.Decode(bytes.NewReader(c.Piece))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 50, 50), piece.Bounds())
+
+	answer := answerOf(t, c.Id)
+	assert.Len(t, answer, 1)
+	assert.Equal(t, c.Y, answer[0].Y)
+	assert.NoError(t, x.VerifySlider(ctx, s, c.Id, answer[0].X+4, nil))
+	assert.ErrorIs(t, x.VerifySlider(ctx, s, c.Id, answer[0].X, nil), captcha.ErrCaptchaNotExists)
+}
+
+func TestCreateSliderSize(t *testing.T) {
+	ctx := context.TODO()
+	for _, size := range [][3]int{{200, 60, 50}, {100, 150, 50}, {300, 150, 0}} {
+		_, err := x.CreateSlider(ctx, captcha.NewSlider(captcha.SetSliderSize(size[0], size[1], size[2])), time.Second*60)
+		assert.Error(t, err)
+	}
+	_, err := x.CreateSlider(ctx, captcha.NewSlider(captcha.SetSliderSize(120, 70, 50)), time.Second*60)
+	assert.NoError(t, err)
+}
+
+func TestSliderConsumed(t *testing.T) {
+	ctx := context.TODO()
+	s := captcha.NewSlider(captcha.SetSliderTolerance(2))
+	c, err := x.CreateSlider(ctx, s, time.Second*60)
+	assert.NoError(t, err)
+	answer := answerOf(t, c.Id)
+	assert.ErrorIs(t, x.VerifySlider(ctx, s, c.Id, answer[0].X+3, nil), captcha.ErrCaptchaInconsistent)
+	assert.ErrorIs(t, x.VerifySlider(ctx, s, c.Id, answer[0].X, nil), captcha.ErrCaptchaNotExists)
+}
+
+func TestSliderTrail(t *testing.T) {
+	ctx := context.TODO()
+	s := captcha.NewSlider(captcha.SetSliderTrail(time.Millisecond*300, time.Second*5))
+	trail := func(to int, duration int64, steady bool) (v []captcha.Point) {
+		for i := int64(0); i <= 10; i++ {
+			k := float64(i) / 10
+			if !steady {
+				// ease out like a hand
+				k = 1 - (1-k)*(1-k)
+			}
+			v = append(v, captcha.Point{X: int(float64(to) * k), Y: 3, T: duration * i / 10})
+		}
+		return
+	}
+	cases := []struct {
+		trail func(to int) []captcha.Point
+		err   error
+	}{
+		{func(to int) []captcha.Point { return trail(to, 800, false) }, nil},
+		{func(to int) []captcha.Point { return nil }, captcha.ErrCaptchaInconsistent},
+		{func(to int) []captcha.Point { return trail(to, 100, false) }, captcha.ErrCaptchaInconsistent},
+		{func(to int) []captcha.Point { return trail(to, 8000, false) }, captcha.ErrCaptchaInconsistent},
+		{func(to int) []captcha.Point { return trail(to, 800, true) }, captcha.ErrCaptchaInconsistent},
+		{func(to int) []captcha.Point { return trail(to+40, 800, false) }, captcha.ErrCaptchaInconsistent},
+	}
+	for _, v := range cases {
+		c, err := x.CreateSlider(ctx, s, time.Second*60)
+		assert.NoError(t, err)
+		to := answerOf(t, c.Id)[0].X
+		if v.err == nil {
+			assert.NoError(t, x.VerifySlider(ctx, s, c.Id, to, v.trail(to)))
+		} else {
+			assert.ErrorIs(t, x.VerifySlider(ctx, s, c.Id, to, v.trail(to)), v.err)
+		}
+	}
+}
+
+func TestSliderBackgrounds(t *testing.T) {
+	ctx := context.TODO()
+	s := captcha.NewSlider(
+		captcha.SetSliderSize(200, 100, 40),
+		captcha.SetSliderBackgrounds(image.NewUniform(color.RGBA{R: 10, G: 200, B: 30, A: 255})),
+	)
+	c, err := x.CreateSlider(ctx, s, time.Second*60)
+	assert.NoError(t, err)
+	bg, err := png.Decode(bytes.NewReader(c.Background))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 200, 100), bg.Bounds())
+	r, g, b, _ := bg.At(0, 0).RGBA()
+	assert.Equal(t, []uint32{10, 200, 30}, []uint32{r >> 8, g >> 8, b >> 8})
+}
+
+func TestCreateClick(t *testing.T) {
+	ctx := context.TODO()
+	c := captcha.NewClick()
+	challenge, err := x.CreateClick(ctx, c, time.Second*60)
+	assert.NoError(t, err)
+	assert.Len(t, challenge.Prompt, 3)
+	img, err := png.Decode(bytes.NewReader(challenge.Image))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 300, 150), img.Bounds())
+
+	answer := answerOf(t, challenge.Id)
+	assert.Len(t, answer, 3)
+	points := make([]captcha.Point, len(answer))
+	for i, v := range answer {
+		points[i] = captcha.Point{X: v.X + 10, Y: v.Y - 10}
+	}
+	assert.NoError(t, x.VerifyClick(ctx, c, challenge.Id, points))
+	assert.ErrorIs(t, x.VerifyClick(ctx, c, challenge.Id, points), captcha.ErrCaptchaNotExists)
+}
+
+func TestClickWrongOrder(t *testing.T) {
+	ctx := context.TODO()
+	c := captcha.NewClick(captcha.SetClickCount(2, 4), captcha.SetClickTolerance(10))
+	challenge, err := x.CreateClick(ctx, c, time.Second*60)
+	assert.NoError(t, err)
+	answer := answerOf(t, challenge.Id)
+	assert.ErrorIs(t, x.VerifyClick(ctx, c, challenge.Id, []captcha.Point{answer[1], answer[0]}), captcha.ErrCaptchaInconsistent)
+
+	challenge, err = x.CreateClick(ctx, c, time.Second*60)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, x.VerifyClick(ctx, c, challenge.Id, answerOf(t, challenge.Id)[:1]), captcha.ErrCaptchaInconsistent)
+
+	_, err = x.CreateClick(ctx, captcha.NewClick(captcha.SetClickCount(20, 20)), time.Second*60)
+	assert.Error(t, err)
+}
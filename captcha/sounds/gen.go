@@ -0,0 +1,230 @@
+//go:build ignore
+
+// gen renders the spoken digits 0-9 with a formant synthesizer,
+// as 16 kHz unsigned 8-bit mono PCM, run it by go generate in captcha.
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+)
+
+const rate = 16000
+
+// av, ah and af are relative rms levels, a steady vowel is 1.
+type phone struct {
+	dur        float64 // ms
+	f1, f2, f3 float64
+	av         float64 // voicing
+	ah         float64 // aspiration, through the formants
+	af         float64 // frication, through the fricative resonance
+	ff, fb     float64
+}
+
+var phones = map[string]phone{
+	"IY": {dur: 160, f1: 270, f2: 2290, f3: 3010, av: 1},
+	"IH": {dur: 110, f1: 390, f2: 1990, f3: 2550, av: 1},
+	"EH": {dur: 130, f1: 530, f2: 1840, f3: 2480, av: 1},
+	"AH": {dur: 150, f1: 620, f2: 1190, f3: 2390, av: 1},
+	"AA": {dur: 150, f1: 730, f2: 1090, f3: 2440, av: 1},
+	"AO": {dur: 170, f1: 570, f2: 840, f3: 2410, av: 1},
+	"OH": {dur: 130, f1: 500, f2: 900, f3: 2400, av: 1},
+	"UW": {dur: 200, f1: 300, f2: 870, f3: 2240, av: 1},
+	"R":  {dur: 80, f1: 310, f2: 1060, f3: 1380, av: 0.8},
+	"W":  {dur: 80, f1: 290, f2: 610, f3: 2150, av: 0.7},
+	"N":  {dur: 100, f1: 280, f2: 1700, f3: 2600, av: 0.45},
+	"V":  {dur: 80, f1: 220, f2: 1100, f3: 2080, av: 0.4, af: 0.08, ff: 5000, fb: 4000},
+	"Z":  {dur: 110, f1: 240, f2: 1600, f3: 2600, av: 0.4, af: 0.25, ff: 5000, fb: 1200},
+	"S":  {dur: 140, af: 0.45, ff: 5500, fb: 1500},
+	"F":  {dur: 130, af: 0.15, ff: 6000, fb: 4000},
+	"TH": {dur: 120, af: 0.12, ff: 6500, fb: 4000},
+	"_":  {dur: 50},
+	"TB": {dur: 15, af: 0.5, ff: 4500, fb: 1500},
+	"KB": {dur: 20, af: 0.5, ff: 2000, fb: 800},
+	"HH": {dur: 50, ah: 0.25},
+}
+
+var words = [][]string{
+	{"Z", "IH", "R", "OH", "UW"},
+	{"W", "AH", "N"},
+	{"_", "TB", "HH", "UW"},
+	{"TH", "R", "IY"},
+	{"F", "AO", "R"},
+	{"F", "AA", "IY", "V"},
+	{"S", "IH", "_", "KB", "S"},
+	{"S", "EH", "V", "AH", "N"},
+	{"EH", "IY", "_", "TB"},
+	{"N", "AA", "IY", "N"},
+}
+
+type resonator struct {
+	y1, y2 float64
+}
+
+func (r *resonator) next(x, f, bw float64) float64 {
+	c := -math.Exp(-2 * math.Pi * bw / rate)
+	b := 2 * math.Exp(-math.Pi*bw/rate) * math.Cos(2*math.Pi*f/rate)
+	y := (1-b-c)*x + b*r.y1 + c*r.y2
+	r.y2, r.y1 = r.y1, y
+	return y
+}
+
+// voicing, aspiration and the fricative resonances differ in gain by orders of magnitude,
+// calibrate measures the rms of each path on a steady signal.
+func calibrate(r *rand.Rand) (voicing float64, aspiration float64, frication map[[2]float64]float64) {
+	steady := func(next func() float64) float64 {
+		sum := 0.0
+		for i := 0; i < rate; i++ {
+			v := next()
+			if i >= rate/4 {
+				sum += v * v
+			}
+		}
+		return math.Sqrt(sum / (rate * 3 / 4))
+	}
+	cascade := func(source func() float64) func() float64 {
+		var c1, c2, c3, c4 resonator
+		return func() float64 {
+			v := c1.next(source(), 730, 60)
+			v = c2.next(v, 1090, 90)
+			v = c3.next(v, 2440, 150)
+			return c4.next(v, 3500, 250)
+		}
+	}
+	var g glottis
+	voicing = 1 / steady(cascade(func() float64 {
+		return g.next(120)
+	}))
+	aspiration = 1 / steady(cascade(func() float64 {
+		return r.Float64()*2 - 1
+	}))
+	frication = make(map[[2]float64]float64)
+	for _, p := range phones {
+		if p.af == 0 {
+			continue
+		}
+		var fr resonator
+		frication[[2]float64{p.ff, p.fb}] = 1 / steady(func() float64 {
+			return fr.next(r.Float64()*2-1, p.ff, p.fb)
+		})
+	}
+	return
+}
+
+// glottis is an impulse train through a -12 dB/octave low pass, differentiated for the lip radiation.
+type glottis struct {
+	phase  float64
+	g1, g2 resonator
+	prev   float64
+}
+
+func (x *glottis) next(f0 float64) float64 {
+	x.phase += f0 / rate
+	pulse := 0.0
+	if x.phase >= 1 {
+		x.phase -= 1
+		pulse = 1
+	}
+	v := x.g2.next(x.g1.next(pulse, 0, 100), 0, 100)
+	d := v - x.prev
+	x.prev = v
+	return d
+}
+
+// smooth moves v towards target with the time constant tau in ms.
+func smooth(v, target, tau float64) float64 {
+	return v + (target-v)*(1-math.Exp(-1000/(tau*rate)))
+}
+
+func render(word []string, r *rand.Rand) []float64 {
+	voicing, aspiration, frication := calibrate(r)
+
+	// closures and aspiration take the formants of the following phone
+	segs := make([]phone, len(word))
+	for i, name := range word {
+		segs[i] = phones[name]
+	}
+	for i := len(segs) - 1; i >= 0; i-- {
+		if segs[i].f1 == 0 {
+			segs[i].f1, segs[i].f2, segs[i].f3 = 500, 1500, 2500
+			if i+1 < len(segs) {
+				segs[i].f1, segs[i].f2, segs[i].f3 = segs[i+1].f1, segs[i+1].f2, segs[i+1].f3
+			}
+		}
+	}
+	total := 0.0
+	for _, p := range segs {
+		total += p.dur
+	}
+
+	var (
+		out            []float64
+		f1, f2, f3     = segs[0].f1, segs[0].f2, segs[0].f3
+		av, ah, af     float64
+		ff, fb         = segs[0].ff, segs[0].fb
+		g              glottis
+		c1, c2, c3, c4 resonator
+		fr             resonator
+		elapsed        float64
+	)
+	for _, p := range segs {
+		n := int(p.dur * rate / 1000)
+		if p.ff != 0 {
+			ff, fb = p.ff, p.fb
+		}
+		for i := 0; i < n; i++ {
+			f1 = smooth(f1, p.f1, 20)
+			f2 = smooth(f2, p.f2, 20)
+			f3 = smooth(f3, p.f3, 20)
+			av = smooth(av, p.av, 6)
+			ah = smooth(ah, p.ah, 6)
+			af = smooth(af, p.af, 4)
+
+			// falling pitch with a little jitter
+			glottal := g.next(135 - 40*elapsed/total + r.Float64()*2)
+			noise := r.Float64()*2 - 1
+
+			v := av*voicing*glottal + ah*aspiration*noise
+			v = c1.next(v, f1, 60)
+			v = c2.next(v, f2, 90)
+			v = c3.next(v, f3, 150)
+			v = c4.next(v, 3500, 250)
+			v += af * frication[[2]float64{ff, fb}] * fr.next(noise, ff, fb)
+			out = append(out, v)
+			elapsed += 1000.0 / rate
+		}
+	}
+
+	peak := 0.0
+	for _, v := range out {
+		peak = math.Max(peak, math.Abs(v))
+	}
+	fade := rate / 100
+	for i := range out {
+		out[i] *= 0.9 / peak
+		if i < fade {
+			out[i] *= float64(i) / float64(fade)
+		}
+		if j := len(out) - 1 - i; j < fade {
+			out[i] *= float64(j) / float64(fade)
+		}
+	}
+	return out
+}
+
+func main() {
+	r := rand.New(rand.NewSource(1))
+	for digit, word := range words {
+		samples := render(word, r)
+		b := make([]byte, len(samples))
+		for i, v := range samples {
+			b[i] = uint8(128 + math.Round(v*127))
+		}
+		if err := os.WriteFile(fmt.Sprintf("sounds/%d.pcm", digit), b, 0644); err != nil {
+			log.Fatalln(err)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package captcha
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/cloudwego/hertz/pkg/common/errors"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Work issues hashcash style challenges, signed by Key so that nothing is stored until redemption.
+// The client looks for a nonce that SHA-256 of "challenge:nonce" starts with difficulty zero bits.
+type Work struct {
+	Key []byte
+	// Difficulty is the default number of leading zero bits.
+	Difficulty int
+	// Max caps the difficulty given by Scale.
+	Max int
+	TTL time.Duration
+}
+
+type WorkOption func(x *Work)
+
+func NewWork(key []byte, options ...WorkOption) *Work {
+	x := &Work{
+		Key:        key,
+		Difficulty: 16,
+		Max:        24,
+		TTL:        time.Minute * 5,
+	}
+	for _, v := range options {
+		v(x)
+	}
+	return x
+}
+
+func SetDifficulty(v int) WorkOption {
+	return func(x *Work) {
+		x.Difficulty = v
+	}
+}
+
+func SetMaxDifficulty(v int) WorkOption {
+	return func(x *Work) {
+		x.Max = v
+	}
+}
+
+func SetWorkTTL(v time.Duration) WorkOption {
+	return func(x *Work) {
+		x.TTL = v
+	}
+}
+
+// Scale adds one bit, doubling the work, per count, e.g. the failures counted by locker.
+func (x *Work) Scale(count int64) int {
+	if count < 0 {
+		count = 0
+	}
+	if d := int64(x.Difficulty) + count; d < int64(x.Max) {
+		return int(d)
+	}
+	return x.Max
+}
+
+type Puzzle struct {
+	Challenge  string `json:"challenge"`
+	Difficulty int    `json:"difficulty"`
+	ExpiresAt  int64  `json:"expires_at"`
+}
+
+var (
+	ErrWorkInvalid  = errors.NewPublic("the proof of work is invalid")
+	ErrWorkExpired  = errors.NewPublic("the proof of work is expired")
+	ErrWorkRedeemed = errors.NewPublic("the proof of work has been redeemed")
+	ErrWorkTooEasy  = errors.NewPublic("the proof of work is below the required difficulty")
+)
+
+// Issue signs a challenge of difficulty, it is stateless.
+func (x *Work) Issue(difficulty int) (_ *Puzzle, err error) {
+	if difficulty < 0 || difficulty > 256 {
+		return nil, fmt.Errorf("the difficulty must be in [0, 256]: %d", difficulty)
+	}
+	salt := make([]byte, 16)
+	if _, err = rand.Read(salt); err != nil {
+		return
+	}
+	expiresAt := time.Now().Add(x.TTL).Unix()
+	payload := fmt.Sprintf(`%d.%d.%s`, difficulty, expiresAt, hex.EncodeToString(salt))
+	return &Puzzle{
+		Challenge:  payload + "." + x.sign(payload),
+		Difficulty: difficulty,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+func (x *Work) sign(payload string) string {
+	h := hmac.New(sha256.New, x.Key)
+	h.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// check verifies the signature, expiry and work, and returns the salt and the remaining time.
+// The puzzles are not bound to a caller, so a difficulty under minDifficulty is rejected.
+func (x *Work) check(minDifficulty int, challenge string, nonce string) (salt string, ttl time.Duration, err error) {
+	parts := strings.Split(challenge, ".")
+	if len(parts) != 4 {
+		return "", 0, ErrWorkInvalid
+	}
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(x.sign(payload))) {
+		return "", 0, ErrWorkInvalid
+	}
+	var difficulty, expiresAt int64
+	if difficulty, err = strconv.ParseInt(parts[0], 10, 64); err != nil {
+		return "", 0, ErrWorkInvalid
+	}
+	if expiresAt, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+		return "", 0, ErrWorkInvalid
+	}
+	if ttl = time.Until(time.Unix(expiresAt, 0)); ttl <= 0 {
+		return "", 0, ErrWorkExpired
+	}
+	if difficulty < int64(minDifficulty) {
+		return "", 0, ErrWorkTooEasy
+	}
+	if zeros(work(challenge, nonce)) < int(difficulty) {
+		return "", 0, ErrWorkInvalid
+	}
+	return parts[2], ttl, nil
+}
+
+// RedeemWork verifies the nonce found for the challenge, each challenge is redeemed only once.
+// minDifficulty is the difficulty required of the caller now, e.g. Scale of its failures,
+// so that an easier puzzle issued to another caller or before the failures is rejected.
+func (x *Captcha) RedeemWork(ctx context.Context, w *Work, minDifficulty int,
+	challenge string, nonce string) (err error) {
+	var salt string
+	var ttl time.Duration
+	if salt, ttl, err = w.check(minDifficulty, challenge, nonce); err != nil {
+		return
+	}
+	var n int64
+	if n, err = x.Store.Incr(ctx, x.Key("work:"+salt), ttl); err != nil {
+		return
+	}
+	if n != 1 {
+		return ErrWorkRedeemed
+	}
+	return
+}
+
+// SolveWork finds the nonce on the client side, it takes about 2^difficulty hashes.
+func SolveWork(ctx context.Context, p *Puzzle) (_ string, err error) {
+	for i := uint64(0); ; i++ {
+		if i&0xffff == 0 {
+			if err = ctx.Err(); err != nil {
+				return
+			}
+		}
+		nonce := strconv.FormatUint(i, 16)
+		if zeros(work(p.Challenge, nonce)) >= p.Difficulty {
+			return nonce, nil
+		}
+	}
+}
+
+func work(challenge string, nonce string) [32]byte {
+	return sha256.Sum256([]byte(challenge + ":" + nonce))
+}
+
+func zeros(h [32]byte) (n int) {
+	for _, b := range h {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return
+}
@@ -0,0 +1,85 @@
+package captcha_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/captcha"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWork(t *testing.T) {
+	ctx := context.TODO()
+	w := captcha.NewWork([]byte("6ea3c1f8b2d94e0a"), captcha.SetDifficulty(12))
+	p, err := w.Issue(w.Difficulty)
+	assert.NoError(t, err)
+	assert.Equal(t, 12, p.Difficulty)
+	assert.Greater(t, p.ExpiresAt, time.Now().Unix())
+
+	nonce, err := captcha.SolveWork(ctx, p)
+	assert.NoError(t, err)
+	assert.NoError(t, x.RedeemWork(ctx, w, 12, p.Challenge, nonce))
+	assert.ErrorIs(t, x.RedeemWork(ctx, w, 12, p.Challenge, nonce), captcha.ErrWorkRedeemed)
+}
+
+func TestWorkInvalid(t *testing.T) {
+	ctx := context.TODO()
+	w := captcha.NewWork([]byte("6ea3c1f8b2d94e0a"))
+	p, err := w.Issue(20)
+	assert.NoError(t, err)
+	// hardly any nonce meets 20 bits
+	assert.ErrorIs(t, x.RedeemWork(ctx, w, 0, p.Challenge, "0"), captcha.ErrWorkInvalid)
+
+	easy, err := w.Issue(1)
+	assert.NoError(t, err)
+	nonce, err := captcha.SolveWork(ctx, easy)
+	assert.NoError(t, err)
+	// raising the difficulty breaks the signature
+	forged := strings.Replace(easy.Challenge, "1.", "0.", 1)
+	assert.ErrorIs(t, x.RedeemWork(ctx, w, 0, forged, nonce), captcha.ErrWorkInvalid)
+	other := captcha.NewWork([]byte("0000000000000000"))
+	assert.ErrorIs(t, x.RedeemWork(ctx, other, 0, easy.Challenge, nonce), captcha.ErrWorkInvalid)
+	assert.ErrorIs(t, x.RedeemWork(ctx, w, 0, "abc", nonce), captcha.ErrWorkInvalid)
+
+	_, err = w.Issue(257)
+	assert.Error(t, err)
+}
+
+func TestWorkExpired(t *testing.T) {
+	ctx := context.TODO()
+	w := captcha.NewWork([]byte("6ea3c1f8b2d94e0a"), captcha.SetWorkTTL(-time.Second))
+	p, err := w.Issue(1)
+	assert.NoError(t, err)
+	nonce, err := captcha.SolveWork(ctx, p)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, x.RedeemWork(ctx, w, 0, p.Challenge, nonce), captcha.ErrWorkExpired)
+}
+
+func TestWorkMinDifficulty(t *testing.T) {
+	ctx := context.TODO()
+	w := captcha.NewWork([]byte("6ea3c1f8b2d94e0a"), captcha.SetDifficulty(0), captcha.SetMaxDifficulty(8))
+	// a puzzle issued before the failures, or to another caller
+	easy, err := w.Issue(w.Scale(0))
+	assert.NoError(t, err)
+	nonce, err := captcha.SolveWork(ctx, easy)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, x.RedeemWork(ctx, w, w.Scale(8), easy.Challenge, nonce), captcha.ErrWorkTooEasy)
+
+	// the rejected puzzle is not redeemed
+	assert.NoError(t, x.RedeemWork(ctx, w, w.Scale(0), easy.Challenge, nonce))
+
+	hard, err := w.Issue(w.Scale(8))
+	assert.NoError(t, err)
+	nonce, err = captcha.SolveWork(ctx, hard)
+	assert.NoError(t, err)
+	assert.NoError(t, x.RedeemWork(ctx, w, w.Scale(3), hard.Challenge, nonce))
+}
+
+func TestWorkScale(t *testing.T) {
+	w := captcha.NewWork(nil, captcha.SetDifficulty(10), captcha.SetMaxDifficulty(14))
+	assert.Equal(t, 10, w.Scale(0))
+	assert.Equal(t, 10, w.Scale(-1))
+	assert.Equal(t, 13, w.Scale(3))
+	assert.Equal(t, 14, w.Scale(100))
+}
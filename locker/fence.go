@@ -0,0 +1,86 @@
+package locker
+
+import (
+	"context"
+	"fmt"
+	"github.com/cloudwego/hertz/pkg/common/errors"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// Fence guards the resources written under a Mutex, it remembers the highest Lease.Fence
+// per resource and rejects the holders whose lease has expired meanwhile.
+//
+// The check protects a write only when it is made with the write, as by CheckAndSet for Redis.
+// The other storages compare the token in the write itself, e.g. in MongoDB:
+//
+//	UpdateOne(ctx, bson.M{"_id": id, "fence": bson.M{"$lte": lease.Fence}},
+//		bson.M{"$set": bson.M{"fence": lease.Fence, ...}})
+//
+// where no match is a stale token.
+type Fence struct {
+	RDb redis.UniversalClient
+}
+
+func NewFence(rdb redis.UniversalClient) *Fence {
+	return &Fence{RDb: rdb}
+}
+
+// Key is in the cluster slot of the keys tagged by {resource}.
+func (x *Fence) Key(resource string) string {
+	return fmt.Sprintf(`locker:fenced:{%s}`, resource)
+}
+
+var (
+	ErrStaleFence = errors.NewPublic("the fencing token is stale")
+)
+
+var checkScript = redis.NewScript(`
+if tonumber(redis.call('GET', KEYS[1]) or '0') > tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1])
+return 1
+`)
+
+// Check accepts the token not older than the ones seen for resource, ErrStaleFence otherwise.
+// The same holder may check its token for several writes.
+// Check alone does not reject a stale write made after it, a holder may pause between them.
+func (x *Fence) Check(ctx context.Context, resource string, token int64) (err error) {
+	var n int64
+	if n, err = checkScript.Run(ctx, x.RDb, []string{x.Key(resource)}, token).Int64(); err != nil {
+		return
+	}
+	if n == 0 {
+		return ErrStaleFence
+	}
+	return
+}
+
+var checkAndSetScript = redis.NewScript(`
+if tonumber(redis.call('GET', KEYS[1]) or '0') > tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1])
+if tonumber(ARGV[3]) > 0 then
+	redis.call('SET', KEYS[2], ARGV[2], 'PX', ARGV[3])
+else
+	redis.call('SET', KEYS[2], ARGV[2])
+end
+return 1
+`)
+
+// CheckAndSet writes value to key only when the token is not stale, in the same step as Check.
+// The key must be tagged by {resource} in a cluster, e.g. "orders:{resource}", a ttl of 0 never expires.
+func (x *Fence) CheckAndSet(ctx context.Context, resource string, token int64,
+	key string, value interface{}, ttl time.Duration) (err error) {
+	var n int64
+	if n, err = checkAndSetScript.Run(ctx, x.RDb, []string{x.Key(resource), key},
+		token, value, ttl.Milliseconds()).Int64(); err != nil {
+		return
+	}
+	if n == 0 {
+		return ErrStaleFence
+	}
+	return
+}
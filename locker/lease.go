@@ -0,0 +1,178 @@
+package locker
+
+import (
+	"context"
+	errx "errors"
+	"github.com/redis/go-redis/v9"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Lease is held by its Token until Until, the owner should finish the work before.
+// It is returned by Mutex, RWMutex and Semaphore.
+type Lease struct {
+	Name  string
+	Token string
+	// Fence increases with every lease of Name, see Fence, only set by Mutex.
+	Fence int64
+
+	ttl     time.Duration
+	release func(ctx context.Context) (bool, error)
+	// extend returns the zero time when the lease is not held.
+	extend func(ctx context.Context, ttl time.Duration) (time.Time, error)
+
+	mu    sync.Mutex
+	until time.Time
+	stop  context.CancelFunc
+}
+
+// Until is the local deadline of the lease, the clock drift deducted.
+func (l *Lease) Until() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.until
+}
+
+// Release frees the lease only when it is still held by the owner, and stops KeepAlive.
+func (l *Lease) Release(ctx context.Context) (err error) {
+	l.mu.Lock()
+	if l.stop != nil {
+		l.stop()
+	}
+	l.mu.Unlock()
+	var ok bool
+	if ok, err = l.release(ctx); !ok && err == nil {
+		return ErrNotHeld
+	}
+	return
+}
+
+// Extend resets the lease to ttl, ErrNotHeld when it has expired.
+func (l *Lease) Extend(ctx context.Context, ttl time.Duration) (err error) {
+	var until time.Time
+	if until, err = l.extend(ctx, ttl); until.IsZero() {
+		if err == nil {
+			err = ErrNotHeld
+		}
+		return
+	}
+	l.mu.Lock()
+	l.until = until
+	l.mu.Unlock()
+	return
+}
+
+// KeepAlive extends the lease every third of the TTL until ctx is done or it is released.
+// The channel receives the error and is closed when the lease is lost,
+// the transient failures are retried while the lease is valid.
+func (l *Lease) KeepAlive(ctx context.Context) <-chan error {
+	ctx, cancel := context.WithCancel(ctx)
+	l.mu.Lock()
+	if l.stop != nil {
+		l.stop()
+	}
+	l.stop = cancel
+	l.mu.Unlock()
+	ch := make(chan error, 1)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(l.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			err := l.Extend(ctx, l.ttl)
+			if err == nil || ctx.Err() != nil {
+				continue
+			}
+			if errx.Is(err, ErrNotHeld) || time.Now().After(l.Until()) {
+				ch <- err
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// drift is the clock drift factor deducted from the leases by default.
+const drift = 0.01
+
+// now is the Redis clock in milliseconds, the expiry of the holders in a sorted set.
+const now = `
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+`
+
+// a sorted set lives as long as its latest holder
+const keep = `
+if redis.call('PTTL', KEYS[1]) < tonumber(ARGV[2]) then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+`
+
+var holdScript = redis.NewScript(now + `
+local v = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not v or tonumber(v) <= now then
+	return 0
+end
+redis.call('ZADD', KEYS[1], now + tonumber(ARGV[2]), ARGV[1])
+` + keep + `
+return 1
+`)
+
+var leaveScript = redis.NewScript(now + `
+local v = redis.call('ZSCORE', KEYS[1], ARGV[1])
+redis.call('ZREM', KEYS[1], ARGV[1])
+if not v or tonumber(v) <= now then
+	return 0
+end
+return 1
+`)
+
+// holder is the lease of token in the sorted set key, of Semaphore and the readers of RWMutex.
+func holder(rdb redis.UniversalClient, key string, name string, token string, ttl time.Duration, until time.Time) *Lease {
+	return &Lease{
+		Name:  name,
+		Token: token,
+		ttl:   ttl,
+		until: until,
+		release: func(ctx context.Context) (bool, error) {
+			n, err := leaveScript.Run(ctx, rdb, []string{key}, token).Int64()
+			return n == 1, err
+		},
+		extend: func(ctx context.Context, ttl time.Duration) (_ time.Time, err error) {
+			start := time.Now()
+			var n int64
+			if n, err = holdScript.Run(ctx, rdb, []string{key}, token, ttl.Milliseconds()).Int64(); n != 1 {
+				return
+			}
+			return validity(start, ttl, drift), nil
+		},
+	}
+}
+
+// validity is the local deadline of a lease of ttl requested at start.
+func validity(start time.Time, ttl time.Duration, drift float64) time.Time {
+	return start.Add(ttl - time.Duration(float64(ttl)*drift) - time.Millisecond*2)
+}
+
+// backoff retries try with jitter while it returns ErrNotAcquired, until ctx is done.
+func backoff(ctx context.Context, wait time.Duration, limit time.Duration, try func() (*Lease, error)) (l *Lease, err error) {
+	for {
+		if l, err = try(); !errx.Is(err, ErrNotAcquired) {
+			return
+		}
+		timer := time.NewTimer(wait/2 + rand.N(wait/2+1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		wait = min(wait*2, limit)
+	}
+}
@@ -4,32 +4,89 @@ import (
 	"context"
 	"fmt"
 	"github.com/cloudwego/hertz/pkg/common/errors"
-	"github.com/redis/go-redis/v9"
+	"github.com/weplanx/go/store"
+	"strconv"
 	"time"
 )
 
 type Locker struct {
-	RDb *redis.Client
+	Store store.Store
+	// Policy is required by Fail.
+	Policy *Policy
 }
 
-func New(rdb *redis.Client) *Locker {
-	return &Locker{RDb: rdb}
+type Option func(x *Locker)
+
+func New(s store.Store, options ...Option) *Locker {
+	x := &Locker{Store: s}
+	for _, v := range options {
+		v(x)
+	}
+	return x
+}
+
+func SetPolicy(v *Policy) Option {
+	return func(x *Locker) {
+		x.Policy = v
+	}
 }
 
 func (x *Locker) Key(name string) string {
 	return fmt.Sprintf(`locker:%s`, name)
 }
 
+// Counter is the failures of a name, read with their ttl atomically.
+type Counter struct {
+	Name  string
+	Count int64
+	// TTL is the time until the failures are forgotten, -1 without expiry.
+	TTL time.Duration
+	// Locked is the time until the lock by Fail is lifted, 0 when it is not locked.
+	Locked time.Duration
+}
+
+// Increment counts a failure of name, ttl is set when the counter is created or has none.
+func (x *Locker) Increment(ctx context.Context, name string, ttl time.Duration) (c *Counter, err error) {
+	c = &Counter{Name: name}
+	if c.Count, c.TTL, err = x.Store.IncrTTL(ctx, x.Key(name), ttl); err != nil {
+		return nil, err
+	}
+	return
+}
+
 func (x *Locker) Update(ctx context.Context, name string, ttl time.Duration) int64 {
-	key := x.Key(name)
-	count := x.RDb.Exists(ctx, key).Val()
-	if count != 0 {
-		return x.RDb.Incr(ctx, key).Val()
+	c, err := x.Increment(ctx, name, ttl)
+	if err != nil {
+		return 0
 	}
-	if x.RDb.Set(ctx, key, 1, ttl).Val() == "OK" {
-		return 1
+	return c.Count
+}
+
+// Counters reads the counters of names in one round trip, Count is 0 when there are no failures.
+func (x *Locker) Counters(ctx context.Context, names ...string) (counters []*Counter, err error) {
+	keys := make([]string, 0, len(names)*2)
+	for _, name := range names {
+		keys = append(keys, x.Key(name), x.LockedKey(name))
 	}
-	return 0
+	var entries []store.Entry
+	if entries, err = x.Store.Peek(ctx, keys...); err != nil {
+		return
+	}
+	counters = make([]*Counter, len(names))
+	for i, name := range names {
+		c := &Counter{Name: name}
+		if v := entries[i*2]; v.Exists {
+			if c.Count, err = strconv.ParseInt(v.Value, 10, 64); err != nil {
+				return
+			}
+			c.TTL = v.TTL
+		}
+		if v := entries[i*2+1]; v.Exists {
+			c.Locked = v.TTL
+		}
+		counters[i] = c
+	}
+	return
 }
 
 var (
@@ -37,22 +94,51 @@ var (
 	ErrLocked          = errors.NewPublic("tha locker to be locked")
 )
 
+// Verify returns ErrLocked when the failures reach max or the name is locked by Fail,
+// the time until it is lifted is given by Remaining.
 func (x *Locker) Verify(ctx context.Context, name string, max int64) (err error) {
-	key := x.Key(name)
-	count := x.RDb.Exists(ctx, key).Val()
-	if count == 0 {
+	var counters []*Counter
+	if counters, err = x.Counters(ctx, name); err != nil {
+		return
+	}
+	if c := counters[0]; c.Count == 0 && c.Locked == 0 {
 		return ErrLockerNotExists
 	}
-	var n int64
-	if n, err = x.RDb.Get(ctx, key).Int64(); err != nil {
+	return verify(counters, max)
+}
+
+// VerifyAll verifies several names in one round trip, e.g. the IP, the account and the device,
+// ErrLocked by the longest lock of them.
+func (x *Locker) VerifyAll(ctx context.Context, max int64, names ...string) (counters []*Counter, err error) {
+	if counters, err = x.Counters(ctx, names...); err != nil {
 		return
 	}
-	if n >= max {
-		return ErrLocked
+	return counters, verify(counters, max)
+}
+
+func verify(counters []*Counter, max int64) error {
+	var remaining time.Duration
+	var ok bool
+	for _, c := range counters {
+		d := c.Locked
+		if d == 0 && c.Count >= max {
+			d = c.TTL
+		}
+		if d == 0 {
+			continue
+		}
+		if !ok || d < 0 || (remaining >= 0 && d > remaining) {
+			remaining = d
+		}
+		ok = true
+	}
+	if ok {
+		return locked(remaining)
 	}
-	return
+	return nil
 }
 
 func (x *Locker) Delete(ctx context.Context, name string) int64 {
-	return x.RDb.Del(ctx, x.Key(name)).Val()
+	n, _ := x.Store.Del(ctx, x.Key(name))
+	return n
 }
@@ -5,20 +5,25 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/weplanx/go/locker"
+	"github.com/weplanx/go/store"
 	"log"
 	"os"
+	"sync"
 	"testing"
 	"time"
 )
 
 var x *locker.Locker
+var opts *redis.Options
+var rdb *redis.Client
 
 func TestMain(m *testing.M) {
-	opts, err := redis.ParseURL(os.Getenv("DATABASE_REDIS"))
-	if err != nil {
+	var err error
+	if opts, err = redis.ParseURL(os.Getenv("DATABASE_REDIS")); err != nil {
 		log.Fatalln(err)
 	}
-	x = locker.New(redis.NewClient(opts))
+	rdb = redis.NewClient(opts)
+	x = locker.New(store.NewRedis(rdb))
 	os.Exit(m.Run())
 }
 
@@ -26,7 +31,7 @@ func TestLockerUpdate(t *testing.T) {
 	ctx := context.TODO()
 	n := x.Update(ctx, "dev", time.Second*60)
 	assert.Equal(t, int64(1), n)
-	ttl := x.RDb.TTL(ctx, x.Key("dev")).Val()
+	ttl, _ := x.Store.TTL(ctx, x.Key("dev"))
 	t.Log(ttl.Seconds())
 }
 
@@ -52,9 +57,9 @@ func TestLockerVerifyNotExists(t *testing.T) {
 
 func TestLockerVerifyBad(t *testing.T) {
 	ctx := context.TODO()
-	status := x.RDb.Set(ctx, x.Key("notnumber"), "abc", time.Second*10).Val()
-	assert.Equal(t, "OK", status)
-	err := x.Verify(ctx, "notnumber", 3)
+	err := x.Store.Set(ctx, x.Key("notnumber"), "abc", time.Second*10)
+	assert.NoError(t, err)
+	err = x.Verify(ctx, "notnumber", 3)
 	assert.Error(t, err)
 	t.Log(err)
 }
@@ -63,6 +68,77 @@ func TestLockerDelete(t *testing.T) {
 	ctx := context.TODO()
 	result := x.Delete(ctx, "dev")
 	assert.Equal(t, int64(1), result)
-	count := x.RDb.Exists(ctx, x.Key("dev")).Val()
-	assert.Equal(t, int64(0), count)
+	exists, _ := x.Store.Exists(ctx, x.Key("dev"))
+	assert.False(t, exists)
+}
+
+func TestLockerMemory(t *testing.T) {
+	ctx := context.TODO()
+	m := locker.New(store.NewMemory())
+	assert.ErrorIs(t, m.Verify(ctx, "dev", 2), locker.ErrLockerNotExists)
+	assert.Equal(t, int64(1), m.Update(ctx, "dev", time.Second*60))
+	assert.NoError(t, m.Verify(ctx, "dev", 2))
+	assert.Equal(t, int64(2), m.Update(ctx, "dev", time.Second*60))
+	assert.ErrorIs(t, m.Verify(ctx, "dev", 2), locker.ErrLocked)
+	assert.Equal(t, int64(1), m.Delete(ctx, "dev"))
+}
+
+func TestLockerIncrement(t *testing.T) {
+	ctx := context.TODO()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := x.Increment(ctx, "concurrent", time.Minute)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	c, err := x.Increment(ctx, "concurrent", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "concurrent", c.Name)
+	assert.Equal(t, int64(51), c.Count)
+	assert.Greater(t, c.TTL, time.Second*59)
+	assert.LessOrEqual(t, c.TTL, time.Minute)
+	x.Delete(ctx, "concurrent")
+}
+
+func TestLockerIncrementPersisted(t *testing.T) {
+	ctx := context.TODO()
+	// a counter that lost its expiry
+	assert.NoError(t, x.Store.Set(ctx, x.Key("persisted"), "3", 0))
+	c, err := x.Increment(ctx, "persisted", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), c.Count)
+	assert.Greater(t, c.TTL, time.Second*59)
+	x.Delete(ctx, "persisted")
+}
+
+func TestLockerVerifyAll(t *testing.T) {
+	ctx := context.TODO()
+	for i := 0; i < 2; i++ {
+		x.Update(ctx, "ip:127.0.0.1", time.Minute)
+	}
+	counters, err := x.VerifyAll(ctx, 3, "ip:127.0.0.1", "account:kain", "device:abc")
+	assert.NoError(t, err)
+	assert.Len(t, counters, 3)
+	assert.Equal(t, int64(2), counters[0].Count)
+	assert.Greater(t, counters[0].TTL, time.Second*59)
+	assert.Equal(t, &locker.Counter{Name: "account:kain"}, counters[1])
+
+	for i := 0; i < 3; i++ {
+		x.Update(ctx, "device:abc", time.Minute*5)
+	}
+	x.Update(ctx, "ip:127.0.0.1", time.Minute)
+	counters, err = x.VerifyAll(ctx, 3, "ip:127.0.0.1", "account:kain", "device:abc")
+	assert.ErrorIs(t, err, locker.ErrLocked)
+	assert.Equal(t, int64(3), counters[2].Count)
+	// the longest lock of them
+	remaining, ok := locker.Remaining(err)
+	assert.True(t, ok)
+	assert.Greater(t, remaining, time.Minute*4)
+
+	assert.Equal(t, int64(1), x.Delete(ctx, "ip:127.0.0.1"))
+	assert.Equal(t, int64(1), x.Delete(ctx, "device:abc"))
 }
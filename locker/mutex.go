@@ -0,0 +1,208 @@
+package locker
+
+import (
+	"context"
+	errx "errors"
+	"fmt"
+	"github.com/cloudwego/hertz/pkg/common/errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/weplanx/go/help"
+	"sync"
+	"time"
+)
+
+// Mutex is a lease-based distributed lock, a crashed owner blocks the others until the lease expires.
+// With several independent nodes it is Redlock, a lease needs the majority of them.
+type Mutex struct {
+	Clients []redis.UniversalClient
+	TTL     time.Duration
+	// Backoff is the initial wait of Lock between attempts, doubled with jitter up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Drift is the clock drift factor deducted from the lease.
+	Drift float64
+}
+
+type MutexOption func(x *Mutex)
+
+func NewMutex(rdb redis.UniversalClient, options ...MutexOption) *Mutex {
+	return NewRedlock([]redis.UniversalClient{rdb}, options...)
+}
+
+// NewRedlock locks on independent nodes, not the replicas or shards of one.
+func NewRedlock(clients []redis.UniversalClient, options ...MutexOption) *Mutex {
+	x := &Mutex{
+		Clients:    clients,
+		TTL:        time.Second * 30,
+		Backoff:    time.Millisecond * 50,
+		MaxBackoff: time.Second,
+		Drift:      drift,
+	}
+	for _, v := range options {
+		v(x)
+	}
+	return x
+}
+
+func SetMutexTTL(v time.Duration) MutexOption {
+	return func(x *Mutex) {
+		x.TTL = v
+	}
+}
+
+func SetMutexBackoff(v time.Duration, limit time.Duration) MutexOption {
+	return func(x *Mutex) {
+		x.Backoff = v
+		x.MaxBackoff = limit
+	}
+}
+
+func (x *Mutex) Key(name string) string {
+	return fmt.Sprintf(`locker:mutex:{%s}`, name)
+}
+
+// FenceKey is the counter of the fencing tokens, in the slot of the lock.
+func (x *Mutex) FenceKey(name string) string {
+	return fmt.Sprintf(`locker:fence:{%s}`, name)
+}
+
+var (
+	ErrNotAcquired = errors.NewPublic("the lock is held by another owner")
+	ErrNotHeld     = errors.NewPublic("the lock is not held by the owner")
+)
+
+var acquireScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return redis.call('INCR', KEYS[2])
+end
+return 0
+`)
+
+var raiseScript = redis.NewScript(`
+if tonumber(redis.call('GET', KEYS[1]) or '0') < tonumber(ARGV[1]) then
+	redis.call('SET', KEYS[1], ARGV[1])
+end
+return 1
+`)
+
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+var extendScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// each runs fn on all nodes in parallel, and returns the validity of the lease
+// started now when the majority succeeds, otherwise the zero time.
+func (x *Mutex) each(ctx context.Context, ttl time.Duration,
+	fn func(ctx context.Context, rdb redis.UniversalClient) (bool, error)) (until time.Time, err error) {
+	start := time.Now()
+	// a node that is down must not eat the lease
+	ctx, cancel := context.WithTimeout(ctx, ttl/10)
+	defer cancel()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var n int
+	var errs []error
+	for _, rdb := range x.Clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, e := fn(ctx, rdb)
+			mu.Lock()
+			defer mu.Unlock()
+			if e != nil {
+				errs = append(errs, e)
+			}
+			if ok {
+				n++
+			}
+		}()
+	}
+	wg.Wait()
+	until = validity(start, ttl, x.Drift)
+	if n >= len(x.Clients)/2+1 && until.After(time.Now()) {
+		return
+	}
+	// the nodes that failed decide
+	if len(errs) > len(x.Clients)-len(x.Clients)/2-1 {
+		err = errx.Join(errs...)
+	}
+	return time.Time{}, err
+}
+
+// Acquire tries once, ErrNotAcquired when another owner holds the lock.
+func (x *Mutex) Acquire(ctx context.Context, name string) (l *Lease, err error) {
+	key, fenceKey, token := x.Key(name), x.FenceKey(name), help.Uuid()
+	var mu sync.Mutex
+	var fence int64
+	var until time.Time
+	if until, err = x.each(ctx, x.TTL, func(ctx context.Context, rdb redis.UniversalClient) (bool, error) {
+		n, err := acquireScript.Run(ctx, rdb, []string{key, fenceKey}, token, x.TTL.Milliseconds()).Int64()
+		mu.Lock()
+		fence = max(fence, n)
+		mu.Unlock()
+		return n != 0, err
+	}); !until.IsZero() && len(x.Clients) > 1 {
+		// any two majorities share a node, so the next token of a majority raised to fence is larger
+		var raised time.Time
+		if raised, err = x.each(ctx, x.TTL, func(ctx context.Context, rdb redis.UniversalClient) (bool, error) {
+			err := raiseScript.Run(ctx, rdb, []string{fenceKey}, fence).Err()
+			return err == nil, err
+		}); raised.IsZero() {
+			until = raised
+		}
+	}
+	if until.IsZero() || !until.After(time.Now()) {
+		// the partial leases would block the others until they expire
+		x.release(context.WithoutCancel(ctx), key, token)
+		if err == nil {
+			err = ErrNotAcquired
+		}
+		return
+	}
+	return x.lease(name, token, fence, until), nil
+}
+
+// Lock waits until it acquires or ctx is done.
+func (x *Mutex) Lock(ctx context.Context, name string) (*Lease, error) {
+	return backoff(ctx, x.Backoff, x.MaxBackoff, func() (*Lease, error) {
+		return x.Acquire(ctx, name)
+	})
+}
+
+func (x *Mutex) release(ctx context.Context, key string, token string) (time.Time, error) {
+	return x.each(ctx, x.TTL, func(ctx context.Context, rdb redis.UniversalClient) (bool, error) {
+		n, err := releaseScript.Run(ctx, rdb, []string{key}, token).Int64()
+		return n == 1, err
+	})
+}
+
+func (x *Mutex) lease(name string, token string, fence int64, until time.Time) *Lease {
+	key := x.Key(name)
+	return &Lease{
+		Name:  name,
+		Token: token,
+		Fence: fence,
+		ttl:   x.TTL,
+		until: until,
+		release: func(ctx context.Context) (_ bool, err error) {
+			var until time.Time
+			until, err = x.release(ctx, key, token)
+			return !until.IsZero(), err
+		},
+		extend: func(ctx context.Context, ttl time.Duration) (time.Time, error) {
+			return x.each(ctx, ttl, func(ctx context.Context, rdb redis.UniversalClient) (bool, error) {
+				n, err := extendScript.Run(ctx, rdb, []string{key}, token, ttl.Milliseconds()).Int64()
+				return n == 1, err
+			})
+		},
+	}
+}
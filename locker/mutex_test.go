@@ -0,0 +1,213 @@
+package locker_test
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/help"
+	"github.com/weplanx/go/locker"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMutex(t *testing.T) {
+	ctx := context.TODO()
+	m := locker.NewMutex(rdb)
+	name := help.Uuid()
+	l, err := m.Acquire(ctx, name)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Second*30), l.Until(), time.Second)
+	ttl, err := rdb.PTTL(ctx, m.Key(name)).Result()
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Second*29)
+
+	_, err = m.Acquire(ctx, name)
+	assert.ErrorIs(t, err, locker.ErrNotAcquired)
+
+	assert.NoError(t, l.Release(ctx))
+	assert.ErrorIs(t, l.Release(ctx), locker.ErrNotHeld)
+
+	l, err = m.Acquire(ctx, name)
+	assert.NoError(t, err)
+	assert.NoError(t, l.Release(ctx))
+}
+
+func TestMutexExpired(t *testing.T) {
+	ctx := context.TODO()
+	m := locker.NewMutex(rdb, locker.SetMutexTTL(time.Millisecond*200))
+	name := help.Uuid()
+	l1, err := m.Acquire(ctx, name)
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 300)
+
+	l2, err := m.Acquire(ctx, name)
+	assert.NoError(t, err)
+	// the expired owner must not release or extend the lease of another
+	assert.ErrorIs(t, l1.Release(ctx), locker.ErrNotHeld)
+	assert.ErrorIs(t, l1.Extend(ctx, time.Second), locker.ErrNotHeld)
+	token, err := rdb.Get(ctx, m.Key(name)).Result()
+	assert.NoError(t, err)
+	assert.Equal(t, l2.Token, token)
+
+	assert.NoError(t, l2.Extend(ctx, time.Second*5))
+	ttl, err := rdb.PTTL(ctx, m.Key(name)).Result()
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Second*4)
+	assert.NoError(t, l2.Release(ctx))
+}
+
+func TestMutexLock(t *testing.T) {
+	ctx := context.TODO()
+	m := locker.NewMutex(rdb, locker.SetMutexBackoff(time.Millisecond*10, time.Millisecond*50))
+	name := help.Uuid()
+	var wg sync.WaitGroup
+	var holders atomic.Int64
+	var count atomic.Int64
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l, err := m.Lock(ctx, name)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, int64(1), holders.Add(1))
+			count.Add(1)
+			time.Sleep(time.Millisecond * 20)
+			holders.Add(-1)
+			assert.NoError(t, l.Release(ctx))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(5), count.Load())
+
+	l, err := m.Acquire(ctx, name)
+	assert.NoError(t, err)
+	timeout, cancel := context.WithTimeout(ctx, time.Millisecond*200)
+	defer cancel()
+	_, err = m.Lock(timeout, name)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NoError(t, l.Release(ctx))
+}
+
+func TestMutexKeepAlive(t *testing.T) {
+	ctx := context.TODO()
+	m := locker.NewMutex(rdb, locker.SetMutexTTL(time.Millisecond*300))
+	name := help.Uuid()
+	l, err := m.Acquire(ctx, name)
+	assert.NoError(t, err)
+	lost := l.KeepAlive(ctx)
+	time.Sleep(time.Second)
+	_, err = m.Acquire(ctx, name)
+	assert.ErrorIs(t, err, locker.ErrNotAcquired)
+	assert.True(t, l.Until().After(time.Now()))
+
+	assert.NoError(t, l.Release(ctx))
+	_, ok := <-lost
+	assert.False(t, ok)
+
+	l, err = m.Acquire(ctx, name)
+	assert.NoError(t, err)
+	lost = l.KeepAlive(ctx)
+	rdb.Del(ctx, m.Key(name))
+	select {
+	case err = <-lost:
+		assert.ErrorIs(t, err, locker.ErrNotHeld)
+	case <-time.After(time.Second):
+		t.Error("the lost lease is not reported")
+	}
+}
+
+func node(db int) *redis.Client {
+	o := *opts
+	o.DB = db
+	return redis.NewClient(&o)
+}
+
+func TestRedlock(t *testing.T) {
+	ctx := context.TODO()
+	down := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	nodes := []*redis.Client{node(1), node(2), node(3)}
+	m := locker.NewRedlock([]redis.UniversalClient{nodes[0], nodes[1], down})
+	name := help.Uuid()
+	l, err := m.Acquire(ctx, name)
+	assert.NoError(t, err)
+	assert.NoError(t, l.Extend(ctx, time.Second*10))
+	assert.NoError(t, l.Release(ctx))
+
+	// the majority is down
+	m = locker.NewRedlock([]redis.UniversalClient{nodes[0], down, down})
+	_, err = m.Acquire(ctx, name)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, locker.ErrNotAcquired)
+	assert.Equal(t, int64(0), nodes[0].Exists(ctx, m.Key(name)).Val())
+
+	// the majority is held by another owner, the partial lease is released
+	m = locker.NewRedlock([]redis.UniversalClient{nodes[0], nodes[1], nodes[2]})
+	nodes[0].Set(ctx, m.Key(name), "other", time.Minute)
+	nodes[1].Set(ctx, m.Key(name), "other", time.Minute)
+	_, err = m.Acquire(ctx, name)
+	assert.ErrorIs(t, err, locker.ErrNotAcquired)
+	assert.Equal(t, int64(0), nodes[2].Exists(ctx, m.Key(name)).Val())
+
+	nodes[1].Del(ctx, m.Key(name))
+	l, err = m.Acquire(ctx, name)
+	assert.NoError(t, err)
+	assert.NoError(t, l.Release(ctx))
+	assert.Equal(t, "other", nodes[0].Get(ctx, m.Key(name)).Val())
+	nodes[0].Del(ctx, m.Key(name))
+}
+
+func TestMutexFence(t *testing.T) {
+	ctx := context.TODO()
+	m := locker.NewMutex(rdb, locker.SetMutexTTL(time.Millisecond*200))
+	f := locker.NewFence(rdb)
+	name := help.Uuid()
+	l1, err := m.Acquire(ctx, name)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), l1.Fence)
+	assert.NoError(t, f.Check(ctx, name, l1.Fence))
+
+	// l1 is paused beyond its lease
+	time.Sleep(time.Millisecond * 300)
+	l2, err := m.Acquire(ctx, name)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), l2.Fence)
+	assert.NoError(t, f.Check(ctx, name, l2.Fence))
+	assert.NoError(t, f.Check(ctx, name, l2.Fence))
+	assert.ErrorIs(t, f.Check(ctx, name, l1.Fence), locker.ErrStaleFence)
+
+	// the write is guarded in the same step
+	key := "orders:{" + name + "}"
+	assert.NoError(t, f.CheckAndSet(ctx, name, l2.Fence, key, "l2", time.Minute))
+	assert.ErrorIs(t, f.CheckAndSet(ctx, name, l1.Fence, key, "l1", time.Minute), locker.ErrStaleFence)
+	assert.Equal(t, "l2", rdb.Get(ctx, key).Val())
+	assert.Greater(t, rdb.PTTL(ctx, key).Val(), time.Second*59)
+	rdb.Del(ctx, key)
+	assert.NoError(t, l2.Release(ctx))
+}
+
+func TestRedlockFence(t *testing.T) {
+	ctx := context.TODO()
+	down := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	nodes := []*redis.Client{node(1), node(2), node(3)}
+	m := locker.NewRedlock([]redis.UniversalClient{nodes[0], nodes[1], nodes[2]})
+	name := help.Uuid()
+	nodes[0].Set(ctx, m.FenceKey(name), 10, 0)
+	l, err := m.Acquire(ctx, name)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(11), l.Fence)
+	assert.NoError(t, l.Release(ctx))
+
+	// the node that had the highest counter is down
+	m = locker.NewRedlock([]redis.UniversalClient{down, nodes[1], nodes[2]})
+	l, err = m.Acquire(ctx, name)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), l.Fence)
+	assert.NoError(t, l.Release(ctx))
+	for _, v := range nodes {
+		v.Del(ctx, m.FenceKey(name))
+	}
+}
@@ -0,0 +1,110 @@
+package locker
+
+import (
+	"context"
+	errx "errors"
+	"fmt"
+	"github.com/cloudwego/hertz/pkg/common/errors"
+	"time"
+)
+
+// Policy escalates the lockouts, Max failures within Window lock the name
+// for the next of Lockouts, and the lockouts are forgotten after a quiet period of Decay.
+type Policy struct {
+	Max int64
+	// Window is the decay of the failures, they are forgotten Window after the first one.
+	Window time.Duration
+	// Lockouts are the escalating durations, the last one repeats.
+	Lockouts []time.Duration
+	// Decay starts when a lockout ends.
+	Decay time.Duration
+}
+
+type PolicyOption func(x *Policy)
+
+// NewPolicy locks for 1m, 5m, 30m and then 24h by default.
+func NewPolicy(max int64, window time.Duration, options ...PolicyOption) *Policy {
+	x := &Policy{
+		Max:      max,
+		Window:   window,
+		Lockouts: []time.Duration{time.Minute, time.Minute * 5, time.Minute * 30, time.Hour * 24},
+		Decay:    time.Hour * 24,
+	}
+	for _, v := range options {
+		v(x)
+	}
+	return x
+}
+
+func SetLockouts(v ...time.Duration) PolicyOption {
+	return func(x *Policy) {
+		x.Lockouts = v
+	}
+}
+
+func SetDecay(v time.Duration) PolicyOption {
+	return func(x *Policy) {
+		x.Decay = v
+	}
+}
+
+var (
+	ErrPolicyMissing = errx.New("the locker has no policy")
+	ErrPolicyInvalid = errx.New("the locker policy requires Max and positive Lockouts")
+)
+
+// Lockout is the meta of the ErrLocked returned by Verify.
+type Lockout struct {
+	// Remaining is the time until the lock is lifted, -1 when it never is.
+	Remaining time.Duration
+}
+
+func locked(remaining time.Duration) error {
+	return errors.New(ErrLocked, errors.ErrorTypePublic, &Lockout{Remaining: remaining})
+}
+
+// Remaining returns the time until the lock reported by Verify is lifted, e.g. "try again in 4 minutes".
+func Remaining(err error) (time.Duration, bool) {
+	var e *errors.Error
+	if !errx.As(err, &e) {
+		return 0, false
+	}
+	v, ok := e.Meta.(*Lockout)
+	if !ok {
+		return 0, false
+	}
+	return v.Remaining, true
+}
+
+// LockedKey is apart from the names of Key, in its cluster slot.
+func (x *Locker) LockedKey(name string) string {
+	return fmt.Sprintf(`locker:locked:{%s}`, x.Key(name))
+}
+
+// LockoutsKey is apart from the names of Key, in its cluster slot.
+func (x *Locker) LockoutsKey(name string) string {
+	return fmt.Sprintf(`locker:lockouts:{%s}`, x.Key(name))
+}
+
+// Fail counts a failure of name by Policy, and locks it when the failures reach Max, in one step.
+// It returns the time until the lock is lifted, 0 when it is not locked.
+func (x *Locker) Fail(ctx context.Context, name string) (time.Duration, error) {
+	if x.Policy == nil {
+		return 0, ErrPolicyMissing
+	}
+	if x.Policy.Max <= 0 || len(x.Policy.Lockouts) == 0 {
+		return 0, ErrPolicyInvalid
+	}
+	for _, v := range x.Policy.Lockouts {
+		if v <= 0 {
+			return 0, ErrPolicyInvalid
+		}
+	}
+	return x.Store.Escalate(ctx, x.Key(name), x.LockedKey(name), x.LockoutsKey(name),
+		x.Policy.Max, x.Policy.Window, x.Policy.Lockouts, x.Policy.Decay)
+}
+
+// Unlock lifts the lock and forgets the failures and the lockouts of name, e.g. by the support staff.
+func (x *Locker) Unlock(ctx context.Context, name string) (int64, error) {
+	return x.Store.Del(ctx, x.Key(name), x.LockedKey(name), x.LockoutsKey(name))
+}
@@ -0,0 +1,151 @@
+package locker_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/help"
+	"github.com/weplanx/go/locker"
+	"github.com/weplanx/go/store"
+	"sync"
+	"testing"
+	"time"
+)
+
+func fail(t *testing.T, l *locker.Locker, name string, times int) (d time.Duration) {
+	ctx := context.TODO()
+	for i := 0; i < times; i++ {
+		var err error
+		d, err = l.Fail(ctx, name)
+		assert.NoError(t, err)
+	}
+	return
+}
+
+func TestPolicy(t *testing.T) {
+	ctx := context.TODO()
+	p := locker.NewPolicy(3, time.Minute,
+		locker.SetLockouts(time.Millisecond*200, time.Millisecond*400),
+		locker.SetDecay(time.Millisecond*300),
+	)
+	for _, s := range []store.Store{store.NewRedis(rdb), store.NewMemory()} {
+		l := locker.New(s, locker.SetPolicy(p))
+		name := help.Uuid()
+		assert.Equal(t, time.Duration(0), fail(t, l, name, 2))
+		assert.NoError(t, l.Verify(ctx, name, p.Max))
+
+		assert.Equal(t, time.Millisecond*200, fail(t, l, name, 1))
+		err := l.Verify(ctx, name, p.Max)
+		assert.ErrorIs(t, err, locker.ErrLocked)
+		remaining, ok := locker.Remaining(err)
+		assert.True(t, ok)
+		assert.Greater(t, remaining, time.Millisecond*100)
+		assert.LessOrEqual(t, remaining, time.Millisecond*200)
+		// the failures while locked are not counted
+		assert.Greater(t, fail(t, l, name, 5), time.Duration(0))
+
+		time.Sleep(time.Millisecond * 250)
+		assert.ErrorIs(t, l.Verify(ctx, name, p.Max), locker.ErrLockerNotExists)
+		assert.Equal(t, time.Millisecond*400, fail(t, l, name, 3))
+		time.Sleep(time.Millisecond * 450)
+		// the last lockout repeats
+		assert.Equal(t, time.Millisecond*400, fail(t, l, name, 3))
+
+		// the lockouts decay after the quiet period
+		time.Sleep(time.Millisecond * 750)
+		assert.Equal(t, time.Millisecond*200, fail(t, l, name, 3))
+	}
+}
+
+func TestPolicyUnlock(t *testing.T) {
+	ctx := context.TODO()
+	l := locker.New(store.NewMemory(), locker.SetPolicy(locker.NewPolicy(2, time.Minute)))
+	assert.Equal(t, time.Minute, fail(t, l, "dev", 2))
+	assert.ErrorIs(t, l.Verify(ctx, "dev", 2), locker.ErrLocked)
+
+	n, err := l.Unlock(ctx, "dev")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.ErrorIs(t, l.Verify(ctx, "dev", 2), locker.ErrLockerNotExists)
+	// the escalation starts over
+	assert.Equal(t, time.Minute, fail(t, l, "dev", 2))
+}
+
+func TestVerifyRemaining(t *testing.T) {
+	ctx := context.TODO()
+	l := locker.New(store.NewMemory())
+	for i := 0; i < 3; i++ {
+		l.Update(ctx, "dev", time.Minute)
+	}
+	err := l.Verify(ctx, "dev", 3)
+	assert.ErrorIs(t, err, locker.ErrLocked)
+	remaining, ok := locker.Remaining(err)
+	assert.True(t, ok)
+	assert.Greater(t, remaining, time.Second*59)
+
+	_, ok = locker.Remaining(locker.ErrLockerNotExists)
+	assert.False(t, ok)
+}
+
+func TestPolicyConcurrent(t *testing.T) {
+	ctx := context.TODO()
+	for _, s := range []store.Store{store.NewRedis(rdb), store.NewMemory()} {
+		l := locker.New(s, locker.SetPolicy(locker.NewPolicy(5, time.Minute)))
+		name := help.Uuid()
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := l.Fail(ctx, name)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+		// the first lockout, not escalated by the concurrent failures
+		v, err := s.Get(ctx, l.LockoutsKey(name))
+		assert.NoError(t, err)
+		assert.Equal(t, "1", v)
+		ttl, err := s.TTL(ctx, l.LockedKey(name))
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, ttl, time.Minute)
+		_, err = l.Unlock(ctx, name)
+		assert.NoError(t, err)
+	}
+}
+
+func TestPolicyInvalid(t *testing.T) {
+	ctx := context.TODO()
+	_, err := locker.New(store.NewMemory()).Fail(ctx, "dev")
+	assert.ErrorIs(t, err, locker.ErrPolicyMissing)
+	l := locker.New(store.NewMemory(), locker.SetPolicy(&locker.Policy{Max: 3, Window: time.Minute}))
+	_, err = l.Fail(ctx, "dev")
+	assert.ErrorIs(t, err, locker.ErrPolicyInvalid)
+	l = locker.New(store.NewMemory(), locker.SetPolicy(locker.NewPolicy(3, time.Minute, locker.SetLockouts())))
+	_, err = l.Fail(ctx, "dev")
+	assert.ErrorIs(t, err, locker.ErrPolicyInvalid)
+	l = locker.New(store.NewMemory(), locker.SetPolicy(locker.NewPolicy(3, time.Minute, locker.SetLockouts(0))))
+	_, err = l.Fail(ctx, "dev")
+	assert.ErrorIs(t, err, locker.ErrPolicyInvalid)
+}
+
+func TestPolicyKeys(t *testing.T) {
+	ctx := context.TODO()
+	l := locker.New(store.NewRedis(rdb), locker.SetPolicy(locker.NewPolicy(2, time.Minute)))
+	assert.NotEqual(t, l.Key("acct:locked"), l.LockedKey("acct"))
+	assert.NotEqual(t, l.Key("acct:lockouts"), l.LockoutsKey("acct"))
+	name := "account:" + help.Uuid()
+	assert.Equal(t, time.Minute, fail(t, l, name, 2))
+	assert.ErrorIs(t, l.Verify(ctx, name, 2), locker.ErrLocked)
+	assert.ErrorIs(t, l.Verify(ctx, name+":locked", 2), locker.ErrLockerNotExists)
+
+	// the failures counted past Max by Update escalate too
+	other := "ip:" + help.Uuid()
+	for i := 0; i < 3; i++ {
+		l.Update(ctx, other, time.Minute)
+	}
+	assert.Equal(t, time.Minute, fail(t, l, other, 1))
+	for _, v := range []string{name, other} {
+		_, err := l.Unlock(ctx, v)
+		assert.NoError(t, err)
+	}
+}
@@ -0,0 +1,153 @@
+package locker
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/weplanx/go/help"
+	"time"
+)
+
+// RWMutex is held by many readers or a single writer,
+// a waiting writer holds off the new readers so that they do not starve it.
+type RWMutex struct {
+	RDb redis.UniversalClient
+	TTL time.Duration
+	// Backoff is the initial wait of Lock and RLock between attempts, doubled with jitter up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+type RWMutexOption func(x *RWMutex)
+
+func NewRWMutex(rdb redis.UniversalClient, options ...RWMutexOption) *RWMutex {
+	x := &RWMutex{
+		RDb:        rdb,
+		TTL:        time.Second * 30,
+		Backoff:    time.Millisecond * 50,
+		MaxBackoff: time.Second,
+	}
+	for _, v := range options {
+		v(x)
+	}
+	return x
+}
+
+func SetRWMutexTTL(v time.Duration) RWMutexOption {
+	return func(x *RWMutex) {
+		x.TTL = v
+	}
+}
+
+func SetRWMutexBackoff(v time.Duration, limit time.Duration) RWMutexOption {
+	return func(x *RWMutex) {
+		x.Backoff = v
+		x.MaxBackoff = limit
+	}
+}
+
+func (x *RWMutex) Key(name string) string {
+	return fmt.Sprintf(`locker:rw:{%s}`, name)
+}
+
+// keys are the readers, the writer and the waiting writer of name.
+func (x *RWMutex) keys(name string) []string {
+	key := x.Key(name)
+	return []string{key + ":readers", key + ":writer", key + ":intent"}
+}
+
+var readScript = redis.NewScript(now + `
+if redis.call('EXISTS', KEYS[2]) == 1 or redis.call('EXISTS', KEYS[3]) == 1 then
+	return 0
+end
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+redis.call('ZADD', KEYS[1], now + tonumber(ARGV[2]), ARGV[1])
+` + keep + `
+return 1
+`)
+
+var writeScript = redis.NewScript(now + `
+if redis.call('EXISTS', KEYS[2]) == 1 then
+	return 0
+end
+local intent = redis.call('GET', KEYS[3])
+if intent and intent ~= ARGV[1] then
+	return 0
+end
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+if redis.call('ZCARD', KEYS[1]) > 0 then
+	if ARGV[3] == '1' then
+		redis.call('SET', KEYS[3], ARGV[1], 'PX', ARGV[2])
+	end
+	return 0
+end
+redis.call('DEL', KEYS[3])
+redis.call('SET', KEYS[2], ARGV[1], 'PX', ARGV[2])
+return 1
+`)
+
+// RAcquire takes a read lease, ErrNotAcquired when a writer holds or waits for the lock.
+func (x *RWMutex) RAcquire(ctx context.Context, name string) (l *Lease, err error) {
+	keys, token, start := x.keys(name), help.Uuid(), time.Now()
+	var n int64
+	if n, err = readScript.Run(ctx, x.RDb, keys, token, x.TTL.Milliseconds()).Int64(); err != nil {
+		return
+	}
+	if n == 0 {
+		return nil, ErrNotAcquired
+	}
+	return holder(x.RDb, keys[0], name, token, x.TTL, validity(start, x.TTL, drift)), nil
+}
+
+// RLock waits until it takes a read lease or ctx is done.
+func (x *RWMutex) RLock(ctx context.Context, name string) (*Lease, error) {
+	return backoff(ctx, x.Backoff, x.MaxBackoff, func() (*Lease, error) {
+		return x.RAcquire(ctx, name)
+	})
+}
+
+func (x *RWMutex) acquire(ctx context.Context, name string, token string, wait bool) (l *Lease, err error) {
+	keys, start := x.keys(name), time.Now()
+	var n int64
+	if n, err = writeScript.Run(ctx, x.RDb, keys, token, x.TTL.Milliseconds(), wait).Int64(); err != nil {
+		return
+	}
+	if n == 0 {
+		return nil, ErrNotAcquired
+	}
+	return &Lease{
+		Name:  name,
+		Token: token,
+		ttl:   x.TTL,
+		until: validity(start, x.TTL, drift),
+		release: func(ctx context.Context) (bool, error) {
+			n, err := releaseScript.Run(ctx, x.RDb, keys[1:2], token).Int64()
+			return n == 1, err
+		},
+		extend: func(ctx context.Context, ttl time.Duration) (_ time.Time, err error) {
+			start := time.Now()
+			var n int64
+			if n, err = extendScript.Run(ctx, x.RDb, keys[1:2], token, ttl.Milliseconds()).Int64(); n != 1 {
+				return
+			}
+			return validity(start, ttl, drift), nil
+		},
+	}, nil
+}
+
+// Acquire takes the write lease, ErrNotAcquired when it is held by a writer or readers.
+func (x *RWMutex) Acquire(ctx context.Context, name string) (*Lease, error) {
+	return x.acquire(ctx, name, help.Uuid(), false)
+}
+
+// Lock waits until it takes the write lease or ctx is done, the new readers wait meanwhile.
+func (x *RWMutex) Lock(ctx context.Context, name string) (l *Lease, err error) {
+	token := help.Uuid()
+	if l, err = backoff(ctx, x.Backoff, x.MaxBackoff, func() (*Lease, error) {
+		return x.acquire(ctx, name, token, true)
+	}); err != nil {
+		// let the readers in
+		releaseScript.Run(context.WithoutCancel(ctx), x.RDb, x.keys(name)[2:], token)
+	}
+	return
+}
@@ -0,0 +1,98 @@
+package locker_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/help"
+	"github.com/weplanx/go/locker"
+	"testing"
+	"time"
+)
+
+func TestRWMutex(t *testing.T) {
+	ctx := context.TODO()
+	m := locker.NewRWMutex(rdb)
+	name := help.Uuid()
+	r1, err := m.RAcquire(ctx, name)
+	assert.NoError(t, err)
+	r2, err := m.RAcquire(ctx, name)
+	assert.NoError(t, err)
+	_, err = m.Acquire(ctx, name)
+	assert.ErrorIs(t, err, locker.ErrNotAcquired)
+
+	assert.NoError(t, r1.Release(ctx))
+	assert.NoError(t, r2.Release(ctx))
+	w, err := m.Acquire(ctx, name)
+	assert.NoError(t, err)
+	_, err = m.RAcquire(ctx, name)
+	assert.ErrorIs(t, err, locker.ErrNotAcquired)
+	_, err = m.Acquire(ctx, name)
+	assert.ErrorIs(t, err, locker.ErrNotAcquired)
+
+	assert.NoError(t, w.Extend(ctx, time.Second*5))
+	assert.NoError(t, w.Release(ctx))
+	assert.ErrorIs(t, w.Release(ctx), locker.ErrNotHeld)
+	r1, err = m.RAcquire(ctx, name)
+	assert.NoError(t, err)
+	assert.NoError(t, r1.Release(ctx))
+}
+
+func TestRWMutexWriterWaits(t *testing.T) {
+	ctx := context.TODO()
+	m := locker.NewRWMutex(rdb, locker.SetRWMutexBackoff(time.Millisecond*10, time.Millisecond*20))
+	name := help.Uuid()
+	r, err := m.RAcquire(ctx, name)
+	assert.NoError(t, err)
+
+	done := make(chan *locker.Lease)
+	go func() {
+		w, err := m.Lock(ctx, name)
+		assert.NoError(t, err)
+		done <- w
+	}()
+	time.Sleep(time.Millisecond * 100)
+	// the waiting writer holds off the new readers
+	_, err = m.RAcquire(ctx, name)
+	assert.ErrorIs(t, err, locker.ErrNotAcquired)
+
+	assert.NoError(t, r.Release(ctx))
+	w := <-done
+	assert.NoError(t, w.Release(ctx))
+	r, err = m.RAcquire(ctx, name)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Release(ctx))
+}
+
+func TestRWMutexWriterGivesUp(t *testing.T) {
+	ctx := context.TODO()
+	m := locker.NewRWMutex(rdb, locker.SetRWMutexBackoff(time.Millisecond*10, time.Millisecond*20))
+	name := help.Uuid()
+	r, err := m.RAcquire(ctx, name)
+	assert.NoError(t, err)
+	timeout, cancel := context.WithTimeout(ctx, time.Millisecond*100)
+	defer cancel()
+	_, err = m.Lock(timeout, name)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	r2, err := m.RAcquire(ctx, name)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Release(ctx))
+	assert.NoError(t, r2.Release(ctx))
+}
+
+func TestRWMutexExpired(t *testing.T) {
+	ctx := context.TODO()
+	m := locker.NewRWMutex(rdb, locker.SetRWMutexTTL(time.Millisecond*200))
+	name := help.Uuid()
+	_, err := m.RAcquire(ctx, name)
+	assert.NoError(t, err)
+	// the reader crashed
+	time.Sleep(time.Millisecond * 300)
+	w, err := m.Acquire(ctx, name)
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 300)
+	r, err := m.RAcquire(ctx, name)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, w.Release(ctx), locker.ErrNotHeld)
+	assert.NoError(t, r.Release(ctx))
+}
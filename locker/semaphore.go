@@ -0,0 +1,93 @@
+package locker
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/weplanx/go/help"
+	"time"
+)
+
+// Semaphore admits at most Permits holders of a name at once, the lease of a crashed holder expires.
+type Semaphore struct {
+	RDb     redis.UniversalClient
+	Permits int64
+	TTL     time.Duration
+	// Backoff is the initial wait of Lock between attempts, doubled with jitter up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+type SemaphoreOption func(x *Semaphore)
+
+func NewSemaphore(rdb redis.UniversalClient, permits int64, options ...SemaphoreOption) *Semaphore {
+	x := &Semaphore{
+		RDb:        rdb,
+		Permits:    permits,
+		TTL:        time.Second * 30,
+		Backoff:    time.Millisecond * 50,
+		MaxBackoff: time.Second,
+	}
+	for _, v := range options {
+		v(x)
+	}
+	return x
+}
+
+func SetSemaphoreTTL(v time.Duration) SemaphoreOption {
+	return func(x *Semaphore) {
+		x.TTL = v
+	}
+}
+
+func SetSemaphoreBackoff(v time.Duration, limit time.Duration) SemaphoreOption {
+	return func(x *Semaphore) {
+		x.Backoff = v
+		x.MaxBackoff = limit
+	}
+}
+
+func (x *Semaphore) Key(name string) string {
+	return fmt.Sprintf(`locker:semaphore:{%s}`, name)
+}
+
+var semaphoreScript = redis.NewScript(now + `
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+if redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[3]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], now + tonumber(ARGV[2]), ARGV[1])
+` + keep + `
+return 1
+`)
+
+// Acquire takes a permit, ErrNotAcquired when all are held.
+func (x *Semaphore) Acquire(ctx context.Context, name string) (l *Lease, err error) {
+	key, token, start := x.Key(name), help.Uuid(), time.Now()
+	var n int64
+	if n, err = semaphoreScript.Run(ctx, x.RDb, []string{key},
+		token, x.TTL.Milliseconds(), x.Permits).Int64(); err != nil {
+		return
+	}
+	if n == 0 {
+		return nil, ErrNotAcquired
+	}
+	return holder(x.RDb, key, name, token, x.TTL, validity(start, x.TTL, drift)), nil
+}
+
+// Lock waits until it takes a permit or ctx is done.
+func (x *Semaphore) Lock(ctx context.Context, name string) (*Lease, error) {
+	return backoff(ctx, x.Backoff, x.MaxBackoff, func() (*Lease, error) {
+		return x.Acquire(ctx, name)
+	})
+}
+
+var countScript = redis.NewScript(now + `
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+return redis.call('ZCARD', KEYS[1])
+`)
+
+// Count returns the number of the permits held.
+func (x *Semaphore) Count(ctx context.Context, name string) (int64, error) {
+	return countScript.Run(ctx, x.RDb, []string{x.Key(name)}).Int64()
+}
@@ -0,0 +1,83 @@
+package locker_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/help"
+	"github.com/weplanx/go/locker"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSemaphore(t *testing.T) {
+	ctx := context.TODO()
+	s := locker.NewSemaphore(rdb, 2)
+	name := help.Uuid()
+	l1, err := s.Acquire(ctx, name)
+	assert.NoError(t, err)
+	l2, err := s.Acquire(ctx, name)
+	assert.NoError(t, err)
+	_, err = s.Acquire(ctx, name)
+	assert.ErrorIs(t, err, locker.ErrNotAcquired)
+	n, err := s.Count(ctx, name)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	assert.NoError(t, l1.Release(ctx))
+	assert.ErrorIs(t, l1.Release(ctx), locker.ErrNotHeld)
+	l3, err := s.Acquire(ctx, name)
+	assert.NoError(t, err)
+	assert.NoError(t, l2.Release(ctx))
+	assert.NoError(t, l3.Release(ctx))
+}
+
+func TestSemaphoreExpired(t *testing.T) {
+	ctx := context.TODO()
+	s := locker.NewSemaphore(rdb, 1, locker.SetSemaphoreTTL(time.Millisecond*200))
+	name := help.Uuid()
+	l1, err := s.Acquire(ctx, name)
+	assert.NoError(t, err)
+	// the holder crashed
+	time.Sleep(time.Millisecond * 300)
+	l2, err := s.Acquire(ctx, name)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, l1.Extend(ctx, time.Second), locker.ErrNotHeld)
+	assert.ErrorIs(t, l1.Release(ctx), locker.ErrNotHeld)
+
+	lost := l2.KeepAlive(ctx)
+	time.Sleep(time.Millisecond * 500)
+	_, err = s.Acquire(ctx, name)
+	assert.ErrorIs(t, err, locker.ErrNotAcquired)
+	assert.NoError(t, l2.Release(ctx))
+	_, ok := <-lost
+	assert.False(t, ok)
+}
+
+func TestSemaphoreLock(t *testing.T) {
+	ctx := context.TODO()
+	s := locker.NewSemaphore(rdb, 3, locker.SetSemaphoreBackoff(time.Millisecond*10, time.Millisecond*50))
+	name := help.Uuid()
+	var wg sync.WaitGroup
+	var holders, peak atomic.Int64
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l, err := s.Lock(ctx, name)
+			if !assert.NoError(t, err) {
+				return
+			}
+			n := holders.Add(1)
+			for v := peak.Load(); n > v && !peak.CompareAndSwap(v, n); v = peak.Load() {
+			}
+			time.Sleep(time.Millisecond * 20)
+			holders.Add(-1)
+			assert.NoError(t, l.Release(ctx))
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, peak.Load(), int64(3))
+	assert.Greater(t, peak.Load(), int64(1))
+}
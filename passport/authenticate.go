@@ -0,0 +1,164 @@
+package passport
+
+import (
+	"context"
+	errx "errors"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/errors"
+	"strings"
+)
+
+// Extractor reads the token from the request, empty when absent.
+type Extractor func(c *app.RequestContext) string
+
+func FromHeader(name string) Extractor {
+	return func(c *app.RequestContext) string {
+		v := string(c.GetHeader(name))
+		for _, scheme := range []string{"Bearer ", "DPoP "} {
+			if len(v) > len(scheme) && strings.EqualFold(v[:len(scheme)], scheme) {
+				return v[len(scheme):]
+			}
+		}
+		return v
+	}
+}
+
+func FromCookie(name string) Extractor {
+	return func(c *app.RequestContext) string {
+		return string(c.Cookie(name))
+	}
+}
+
+func FromQuery(name string) Extractor {
+	return func(c *app.RequestContext) string {
+		return c.Query(name)
+	}
+}
+
+// SetExtractors tries each extractor in order, the default is
+// the Authorization header followed by the access_token cookie.
+func SetExtractors(v ...Extractor) Option {
+	return func(x *Passport) {
+		x.Extractors = v
+	}
+}
+
+func SetSkipper(v func(ctx context.Context, c *app.RequestContext) bool) Option {
+	return func(x *Passport) {
+		x.Skipper = v
+	}
+}
+
+// SetSkipPaths skips public routes, matching the request path or the route pattern.
+func SetSkipPaths(paths ...string) Option {
+	skip := make(map[string]bool, len(paths))
+	for _, v := range paths {
+		skip[v] = true
+	}
+	return SetSkipper(func(ctx context.Context, c *app.RequestContext) bool {
+		return skip[string(c.Path())] || skip[c.FullPath()]
+	})
+}
+
+const ClaimsKey = "passport"
+
+var (
+	ErrAuthMissingToken = errors.NewPublic("the token is missing")
+	ErrAuthInvalidToken = errors.NewPublic("the token is invalid")
+	ErrAuthInvalidProof = errors.NewPublic("the proof of possession is invalid")
+)
+
+// Authenticate verifies the token, then the denylist, the proof of possession
+// and the session when set, and stores the Claims on the request context for GetClaims.
+func (x *Passport) Authenticate() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		if x.Skipper != nil && x.Skipper(ctx, c) {
+			c.Next(ctx)
+			return
+		}
+
+		var tokenString string
+		for _, extract := range x.Extractors {
+			if tokenString = extract(c); tokenString != "" {
+				break
+			}
+		}
+		if tokenString == "" {
+			c.Error(ErrAuthMissingToken)
+			c.Abort()
+			return
+		}
+
+		claims, err := x.authorize(ctx, c, tokenString)
+		if err != nil {
+			switch {
+			case errx.Is(err, ErrDPoPInvalid), errx.Is(err, ErrDPoPReplay):
+				c.Error(ErrAuthInvalidProof)
+			case errx.Is(err, ErrTokenRevoked), errx.Is(err, ErrSessionNotExists):
+				c.Error(ErrAuthInvalidToken)
+			default:
+				c.Error(err)
+			}
+			c.Abort()
+			return
+		}
+
+		c.Set(ClaimsKey, claims)
+		c.Next(ctx)
+	}
+}
+
+func (x *Passport) authorize(ctx context.Context, c *app.RequestContext, tokenString string) (claims Claims, err error) {
+	if claims, err = x.Verify(tokenString); err != nil {
+		return claims, ErrAuthInvalidToken
+	}
+	if err = x.checkRevoked(ctx, &claims); err != nil {
+		return
+	}
+	if claims.Cnf != nil {
+		if err = x.verifyProof(ctx, c, tokenString, claims.Cnf.Jkt); err != nil {
+			return
+		}
+	}
+	if x.Sessions != nil {
+		if err = x.Sessions.Touch(ctx, claims.ActiveId, claims.ID, c.ClientIP()); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// verifyProof checks the DPoP header of a sender constrained token.
+func (x *Passport) verifyProof(ctx context.Context, c *app.RequestContext, tokenString string, jkt string) (err error) {
+	proof := string(c.GetHeader("DPoP"))
+	if x.DPoP == nil || proof == "" {
+		return ErrDPoPInvalid
+	}
+	var thumbprint string
+	if thumbprint, err = x.DPoP.Verify(ctx, proof, string(c.Method()), requestURL(c), tokenString); err != nil {
+		return
+	}
+	if thumbprint != jkt {
+		return ErrDPoPInvalid
+	}
+	return
+}
+
+// requestURL is the URL seen by the client, the scheme is taken from
+// X-Forwarded-Proto when TLS terminates at a proxy.
+func requestURL(c *app.RequestContext) string {
+	scheme := string(c.GetHeader("X-Forwarded-Proto"))
+	if scheme == "" {
+		scheme = string(c.URI().Scheme())
+	}
+	return scheme + "://" + string(c.Host()) + string(c.URI().Path())
+}
+
+func GetClaims(c *app.RequestContext) (claims Claims, ok bool) {
+	var v interface{}
+	if v, ok = c.Get(ClaimsKey); !ok {
+		return
+	}
+	claims, ok = v.(Claims)
+	return
+}
@@ -0,0 +1,90 @@
+package passport_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/help"
+	"github.com/weplanx/go/passport"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func newAuthEngine(x *passport.Passport) *route.Engine {
+	engine := route.NewEngine(config.NewOptions([]config.Option{}))
+	engine.Use(help.ErrorHandler(), x.Authenticate())
+	handler := func(ctx context.Context, c *app.RequestContext) {
+		claims, ok := passport.GetClaims(c)
+		if !ok {
+			c.JSON(http.StatusOK, help.Ok())
+			return
+		}
+		c.JSON(http.StatusOK, claims)
+	}
+	engine.GET("/login", handler)
+	engine.GET("/users/:id", handler)
+	return engine
+}
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.TODO()
+	x := passport.New(
+		passport.SetIssuer("dev"),
+		passport.SetKey(key1),
+		passport.SetExtractors(
+			passport.FromHeader("Authorization"),
+			passport.FromCookie("access_token"),
+			passport.FromQuery("access_token"),
+		),
+		passport.SetSkipPaths("/login"),
+		passport.SetDenylist(passport.NewMemoryDenylist()),
+	)
+	engine := newAuthEngine(x)
+	ts, err := x.Create(passport.NewClaims(userId1, time.Hour).SetJTI(jti1))
+	assert.NoError(t, err)
+
+	for _, header := range []ut.Header{
+		{Key: "Authorization", Value: "Bearer " + ts},
+		{Key: "Cookie", Value: "access_token=" + ts},
+	} {
+		resp := ut.PerformRequest(engine, "GET", "/users/1", nil, header).Result()
+		assert.Equal(t, http.StatusOK, resp.StatusCode())
+		var claims passport.Claims
+		assert.NoError(t, json.Unmarshal(resp.Body(), &claims))
+		assert.Equal(t, userId1, claims.ActiveId)
+	}
+	resp := ut.PerformRequest(engine, "GET", "/users/1?access_token="+ts, nil).Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+
+	resp = ut.PerformRequest(engine, "GET", "/login", nil).Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.JSONEq(t, `{"code":0,"message":"ok"}`, string(resp.Body()))
+
+	resp = ut.PerformRequest(engine, "GET", "/users/1", nil).Result()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+	assert.JSONEq(t, `{"code":0,"message":"the token is missing"}`, string(resp.Body()))
+
+	resp = ut.PerformRequest(engine, "GET", "/users/1", nil,
+		ut.Header{Key: "Authorization", Value: "Bearer " + otherTokenOf(t)}).Result()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+	assert.JSONEq(t, `{"code":0,"message":"the token is invalid"}`, string(resp.Body()))
+
+	claims, err := x.Verify(ts)
+	assert.NoError(t, err)
+	assert.NoError(t, x.Revoke(ctx, claims))
+	resp = ut.PerformRequest(engine, "GET", "/users/1", nil,
+		ut.Header{Key: "Authorization", Value: "Bearer " + ts}).Result()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+	assert.JSONEq(t, `{"code":0,"message":"the token is invalid"}`, string(resp.Body()))
+}
+
+func otherTokenOf(t *testing.T) string {
+	ts, err := x2.Create(passport.NewClaims(userId2, time.Hour))
+	assert.NoError(t, err)
+	return ts
+}
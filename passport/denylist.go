@@ -0,0 +1,215 @@
+package passport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Denylist keeps revoked tokens until they would have expired anyway.
+type Denylist interface {
+	// Revoke denies the jti until expiresAt.
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	// RevokeBefore denies every token of activeId issued up to the end of the second of t,
+	// iat has the precision of seconds, so a token issued earlier within it is denied as well.
+	// ttl should cover the longest token lifetime.
+	RevokeBefore(ctx context.Context, activeId string, t time.Time, ttl time.Duration) error
+	// RevokedBefore returns the zero time when nothing is denied.
+	RevokedBefore(ctx context.Context, activeId string) (time.Time, error)
+}
+
+// SetDenylist makes VerifyContext reject revoked tokens.
+func SetDenylist(v Denylist) Option {
+	return func(x *Passport) {
+		x.Denylist = v
+	}
+}
+
+var (
+	ErrTokenRevoked    = errors.New("the token has been revoked")
+	ErrMissingJTI      = errors.New("the token has no jti")
+	ErrMissingDenylist = errors.New("the denylist is not set")
+)
+
+// VerifyContext verifies the token and then consults the Denylist.
+func (x *Passport) VerifyContext(ctx context.Context, tokenString string) (claims Claims, err error) {
+	if claims, err = x.Verify(tokenString); err != nil {
+		return
+	}
+	if err = x.checkRevoked(ctx, &claims); err != nil {
+		return
+	}
+	return
+}
+
+func (x *Passport) checkRevoked(ctx context.Context, claims *Claims) (err error) {
+	if x.Denylist == nil {
+		return
+	}
+	if claims.ID != "" {
+		var revoked bool
+		if revoked, err = x.Denylist.IsRevoked(ctx, claims.ID); err != nil {
+			return
+		}
+		if revoked {
+			return ErrTokenRevoked
+		}
+	}
+	var before time.Time
+	if before, err = x.Denylist.RevokedBefore(ctx, claims.ActiveId); err != nil {
+		return
+	}
+	if !before.IsZero() && (claims.IssuedAt == nil || claims.IssuedAt.Unix() <= before.Unix()) {
+		return ErrTokenRevoked
+	}
+	return
+}
+
+// Revoke denies the token, e.g. on logout.
+func (x *Passport) Revoke(ctx context.Context, claims Claims) error {
+	if x.Denylist == nil {
+		return ErrMissingDenylist
+	}
+	if claims.ID == "" {
+		return ErrMissingJTI
+	}
+	expiresAt := time.Now()
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	return x.Denylist.Revoke(ctx, claims.ID, expiresAt)
+}
+
+// RevokeBefore denies every token of activeId issued until now, e.g. on password change.
+// The tokens issued within the current second are denied too, so it returns
+// when the second is over and the new token can be created.
+func (x *Passport) RevokeBefore(ctx context.Context, activeId string, ttl time.Duration) (err error) {
+	if x.Denylist == nil {
+		return ErrMissingDenylist
+	}
+	now := time.Now()
+	if err = x.Denylist.RevokeBefore(ctx, activeId, now, ttl); err != nil {
+		return
+	}
+	timer := time.NewTimer(time.Until(now.Truncate(time.Second).Add(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+	}
+	return
+}
+
+type RedisDenylist struct {
+	RDb *redis.Client
+}
+
+func NewRedisDenylist(rdb *redis.Client) *RedisDenylist {
+	return &RedisDenylist{RDb: rdb}
+}
+
+func (x *RedisDenylist) Key(name string) string {
+	return fmt.Sprintf(`passport:revoked:%s`, name)
+}
+
+func (x *RedisDenylist) SubjectKey(name string) string {
+	return fmt.Sprintf(`passport:revoked_before:%s`, name)
+}
+
+func (x *RedisDenylist) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return x.RDb.Set(ctx, x.Key(jti), 1, ttl).Err()
+}
+
+func (x *RedisDenylist) IsRevoked(ctx context.Context, jti string) (_ bool, err error) {
+	var n int64
+	if n, err = x.RDb.Exists(ctx, x.Key(jti)).Result(); err != nil {
+		return
+	}
+	return n != 0, nil
+}
+
+func (x *RedisDenylist) RevokeBefore(ctx context.Context, activeId string, t time.Time, ttl time.Duration) error {
+	return x.RDb.Set(ctx, x.SubjectKey(activeId), t.Unix(), ttl).Err()
+}
+
+func (x *RedisDenylist) RevokedBefore(ctx context.Context, activeId string) (_ time.Time, err error) {
+	var v string
+	if v, err = x.RDb.Get(ctx, x.SubjectKey(activeId)).Result(); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return time.Time{}, nil
+		}
+		return
+	}
+	var sec int64
+	if sec, err = strconv.ParseInt(v, 10, 64); err != nil {
+		return
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// MemoryDenylist is for tests and single instance deployments.
+type MemoryDenylist struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	value     time.Time
+	expiresAt time.Time
+}
+
+func NewMemoryDenylist() *MemoryDenylist {
+	return &MemoryDenylist{entries: make(map[string]memoryEntry)}
+}
+
+func (x *MemoryDenylist) set(key string, value time.Time, expiresAt time.Time) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	now := time.Now()
+	for k, v := range x.entries {
+		if !v.expiresAt.After(now) {
+			delete(x.entries, k)
+		}
+	}
+	x.entries[key] = memoryEntry{value: value, expiresAt: expiresAt}
+}
+
+func (x *MemoryDenylist) get(key string) (time.Time, bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	v, ok := x.entries[key]
+	if !ok || !v.expiresAt.After(time.Now()) {
+		return time.Time{}, false
+	}
+	return v.value, true
+}
+
+func (x *MemoryDenylist) Revoke(_ context.Context, jti string, expiresAt time.Time) error {
+	x.set("jti:"+jti, expiresAt, expiresAt)
+	return nil
+}
+
+func (x *MemoryDenylist) IsRevoked(_ context.Context, jti string) (bool, error) {
+	_, ok := x.get("jti:" + jti)
+	return ok, nil
+}
+
+func (x *MemoryDenylist) RevokeBefore(_ context.Context, activeId string, t time.Time, ttl time.Duration) error {
+	x.set("sub:"+activeId, t, time.Now().Add(ttl))
+	return nil
+}
+
+func (x *MemoryDenylist) RevokedBefore(_ context.Context, activeId string) (time.Time, error) {
+	t, _ := x.get("sub:" + activeId)
+	return t, nil
+}
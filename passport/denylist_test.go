@@ -0,0 +1,88 @@
+package passport_test
+
+import (
+	"context"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/help"
+	"github.com/weplanx/go/passport"
+	"testing"
+	"time"
+)
+
+func testDenylist(t *testing.T, denylist passport.Denylist) {
+	ctx := context.TODO()
+	x := passport.New(
+		passport.SetIssuer("dev"),
+		passport.SetKey(key1),
+		passport.SetDenylist(denylist),
+	)
+	activeId := help.Uuid()
+	ts1, err := x.Create(passport.NewClaims(activeId, time.Hour).SetJTI(help.Uuid()))
+	assert.NoError(t, err)
+	// issued before the password change
+	claims2 := passport.NewClaims(activeId, time.Hour).SetJTI(help.Uuid())
+	claims2.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Second))
+	ts2, err := x.Create(claims2)
+	assert.NoError(t, err)
+
+	claims, err := x.VerifyContext(ctx, ts1)
+	assert.NoError(t, err)
+	assert.NoError(t, x.Revoke(ctx, claims))
+	_, err = x.VerifyContext(ctx, ts1)
+	assert.ErrorIs(t, err, passport.ErrTokenRevoked)
+	// Verify alone does not consult the denylist.
+	_, err = x.Verify(ts1)
+	assert.NoError(t, err)
+	_, err = x.VerifyContext(ctx, ts2)
+	assert.NoError(t, err)
+
+	// e.g. password change
+	now := time.Now()
+	assert.NoError(t, denylist.RevokeBefore(ctx, activeId, now, time.Hour))
+	_, err = x.VerifyContext(ctx, ts2)
+	assert.ErrorIs(t, err, passport.ErrTokenRevoked)
+	// e.g. stolen, issued earlier within the same second
+	claims3 := passport.NewClaims(activeId, time.Hour).SetJTI(help.Uuid())
+	claims3.IssuedAt = jwt.NewNumericDate(now.Truncate(time.Second))
+	ts3, err := x.Create(claims3)
+	assert.NoError(t, err)
+	_, err = x.VerifyContext(ctx, ts3)
+	assert.ErrorIs(t, err, passport.ErrTokenRevoked)
+
+	assert.NoError(t, x.RevokeBefore(ctx, activeId, time.Hour))
+	_, err = x.VerifyContext(ctx, ts3)
+	assert.ErrorIs(t, err, passport.ErrTokenRevoked)
+	// the new token after the password change
+	ts4, err := x.Create(passport.NewClaims(activeId, time.Hour).SetJTI(help.Uuid()))
+	assert.NoError(t, err)
+	_, err = x.VerifyContext(ctx, ts4)
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, x.Revoke(ctx, *passport.NewClaims(activeId, time.Hour)), passport.ErrMissingJTI)
+
+	jti := help.Uuid()
+	assert.NoError(t, denylist.Revoke(ctx, jti, time.Now().Add(time.Millisecond*500)))
+	revoked, err := denylist.IsRevoked(ctx, jti)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+	time.Sleep(time.Second)
+	revoked, err = denylist.IsRevoked(ctx, jti)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestRedisDenylist(t *testing.T) {
+	testDenylist(t, passport.NewRedisDenylist(rdb))
+}
+
+func TestMemoryDenylist(t *testing.T) {
+	testDenylist(t, passport.NewMemoryDenylist())
+}
+
+func TestRevokeWithoutDenylist(t *testing.T) {
+	err := x1.Revoke(context.TODO(), *passport.NewClaims(userId1, time.Hour).SetJTI(jti1))
+	assert.ErrorIs(t, err, passport.ErrMissingDenylist)
+	err = x1.RevokeBefore(context.TODO(), userId1, time.Hour)
+	assert.ErrorIs(t, err, passport.ErrMissingDenylist)
+}
@@ -0,0 +1,171 @@
+package passport
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/weplanx/go/help"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Confirmation binds the token to the key of the client, jkt is the JWK thumbprint.
+type Confirmation struct {
+	Jkt string `json:"jkt"`
+}
+
+// SetConfirmation makes the token sender constrained, it is only accepted
+// together with a DPoP proof signed by the key of the thumbprint.
+func (x *Claims) SetConfirmation(jkt string) *Claims {
+	x.Cnf = &Confirmation{Jkt: jkt}
+	return x
+}
+
+// SetDPoP verifies the proofs of sender constrained tokens in Authenticate.
+func SetDPoP(v *DPoP) Option {
+	return func(x *Passport) {
+		x.DPoP = v
+	}
+}
+
+// DPoP verifies per request proofs of possession, see RFC 9449.
+type DPoP struct {
+	RDb *redis.Client
+	// Window is the accepted distance between iat of the proof and now.
+	Window time.Duration
+	// CheckNonce is optional, it validates the nonce previously handed out by the server.
+	CheckNonce func(ctx context.Context, nonce string) bool
+}
+
+func NewDPoP(rdb *redis.Client) *DPoP {
+	return &DPoP{
+		RDb:    rdb,
+		Window: time.Minute,
+	}
+}
+
+func (x *DPoP) Key(name string) string {
+	return fmt.Sprintf(`passport:dpop:%s`, name)
+}
+
+type DPoPClaims struct {
+	Htm   string `json:"htm"`
+	Htu   string `json:"htu"`
+	Ath   string `json:"ath,omitempty"`
+	Nonce string `json:"nonce,omitempty"`
+
+	jwt.RegisteredClaims
+}
+
+var (
+	ErrDPoPInvalid = errors.New("the dpop proof is invalid")
+	ErrDPoPReplay  = errors.New("the dpop proof has been used")
+)
+
+// Verify checks the proof against the request and returns the thumbprint of its key,
+// accessToken is the token presented along with the proof, empty when there is none.
+func (x *DPoP) Verify(ctx context.Context, proof string, method string, uri string, accessToken string) (jkt string, err error) {
+	var claims DPoPClaims
+	if _, err = jwt.ParseWithClaims(proof, &claims, func(token *jwt.Token) (interface{}, error) {
+		if typ, _ := token.Header["typ"].(string); typ != "dpop+jwt" {
+			return nil, fmt.Errorf("%w: unexpected typ", ErrDPoPInvalid)
+		}
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+			return nil, fmt.Errorf("%w: symmetric algorithm", ErrDPoPInvalid)
+		}
+		raw, err := json.Marshal(token.Header["jwk"])
+		if err != nil {
+			return nil, err
+		}
+		var jwk JWK
+		if err = json.Unmarshal(raw, &jwk); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDPoPInvalid, err)
+		}
+		jwk.Alg, jwk.Kid = token.Method.Alg(), ""
+		key, err := jwk.SigningKey()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDPoPInvalid, err)
+		}
+		if jkt, err = jwk.Thumbprint(); err != nil {
+			return nil, err
+		}
+		return key.verifyKey(token)
+	}); err != nil {
+		if !errors.Is(err, ErrDPoPInvalid) {
+			err = fmt.Errorf("%w: %v", ErrDPoPInvalid, err)
+		}
+		return "", err
+	}
+
+	if !strings.EqualFold(claims.Htm, method) || normalizeHtu(claims.Htu) != normalizeHtu(uri) {
+		return "", fmt.Errorf("%w: htm or htu does not match the request", ErrDPoPInvalid)
+	}
+	if claims.IssuedAt == nil || claims.ID == "" {
+		return "", fmt.Errorf("%w: missing iat or jti", ErrDPoPInvalid)
+	}
+	if d := time.Since(claims.IssuedAt.Time); d > x.Window || d < -x.Window {
+		return "", fmt.Errorf("%w: iat is out of window", ErrDPoPInvalid)
+	}
+	if accessToken != "" && claims.Ath != ath(accessToken) {
+		return "", fmt.Errorf("%w: ath does not match the token", ErrDPoPInvalid)
+	}
+	if x.CheckNonce != nil && !x.CheckNonce(ctx, claims.Nonce) {
+		return "", fmt.Errorf("%w: nonce is not accepted", ErrDPoPInvalid)
+	}
+
+	var ok bool
+	if ok, err = x.RDb.SetNX(ctx, x.Key(claims.ID), 1, x.Window*2).Result(); err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrDPoPReplay
+	}
+	return
+}
+
+// NewDPoPProof creates the proof on the client side, the key must be asymmetric.
+func NewDPoPProof(method jwt.SigningMethod, key crypto.Signer, htm string, htu string, accessToken string) (_ string, err error) {
+	var jwk JWK
+	if jwk, err = NewJWK(NewSigningKey("", method, key)); err != nil {
+		return
+	}
+	jwk.Use, jwk.Alg = "", ""
+	claims := DPoPClaims{
+		Htm: htm,
+		Htu: htu,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:       help.Uuid(),
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+		},
+	}
+	if accessToken != "" {
+		claims.Ath = ath(accessToken)
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["typ"] = "dpop+jwt"
+	token.Header["jwk"] = jwk
+	return token.SignedString(key)
+}
+
+func ath(accessToken string) string {
+	h := sha256.Sum256([]byte(accessToken))
+	return b64.EncodeToString(h[:])
+}
+
+// normalizeHtu drops query and fragment, and lowercases scheme and host.
+func normalizeHtu(v string) string {
+	u, err := url.Parse(v)
+	if err != nil {
+		return v
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.RawQuery, u.Fragment, u.RawFragment = "", "", ""
+	return u.String()
+}
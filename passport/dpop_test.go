@@ -0,0 +1,150 @@
+package passport_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/help"
+	"github.com/weplanx/go/passport"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestThumbprint(t *testing.T) {
+	// RFC 7638 section 3.1
+	jwk := passport.JWK{
+		Kty: "RSA",
+		N:   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E:   "AQAB",
+		Alg: "RS256",
+		Kid: "2011-04-29",
+	}
+	jkt, err := jwk.Thumbprint()
+	assert.NoError(t, err)
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", jkt)
+	_, err = passport.JWK{Kty: "oct"}.Thumbprint()
+	assert.ErrorIs(t, err, passport.ErrUnsupportedKey)
+}
+
+func TestDPoPVerify(t *testing.T) {
+	ctx := context.TODO()
+	dpop := passport.NewDPoP(rdb)
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	jwk, err := passport.NewJWK(passport.NewSigningKey("", jwt.SigningMethodEdDSA, key))
+	assert.NoError(t, err)
+	expected, err := jwk.Thumbprint()
+	assert.NoError(t, err)
+
+	proof, err := passport.NewDPoPProof(jwt.SigningMethodEdDSA, key, "POST", "https://api.example.com/orders", "token")
+	assert.NoError(t, err)
+	_, err = dpop.Verify(ctx, proof, "GET", "https://api.example.com/orders", "token")
+	assert.ErrorIs(t, err, passport.ErrDPoPInvalid)
+	_, err = dpop.Verify(ctx, proof, "POST", "https://api.example.com/users", "token")
+	assert.ErrorIs(t, err, passport.ErrDPoPInvalid)
+	_, err = dpop.Verify(ctx, proof, "POST", "https://api.example.com/orders", "other")
+	assert.ErrorIs(t, err, passport.ErrDPoPInvalid)
+	jkt, err := dpop.Verify(ctx, proof, "POST", "https://API.example.com/orders?page=1", "token")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, jkt)
+	_, err = dpop.Verify(ctx, proof, "POST", "https://api.example.com/orders", "token")
+	assert.ErrorIs(t, err, passport.ErrDPoPReplay)
+
+	issuedAt := func(id string, iat time.Time) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, passport.DPoPClaims{
+			Htm: "GET",
+			Htu: "https://api.example.com/orders",
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:       id,
+				IssuedAt: jwt.NewNumericDate(iat),
+			},
+		})
+		token.Header["typ"] = "dpop+jwt"
+		token.Header["jwk"] = jwk
+		proof, err := token.SignedString(key)
+		assert.NoError(t, err)
+		return proof
+	}
+	_, err = dpop.Verify(ctx, issuedAt(help.Uuid(), time.Now().Add(-time.Hour)), "GET", "https://api.example.com/orders", "")
+	assert.ErrorIs(t, err, passport.ErrDPoPInvalid)
+	_, err = dpop.Verify(ctx, issuedAt(help.Uuid(), time.Now().Add(time.Hour)), "GET", "https://api.example.com/orders", "")
+	assert.ErrorIs(t, err, passport.ErrDPoPInvalid)
+	// the clock of the client is slightly fast
+	_, err = dpop.Verify(ctx, issuedAt(help.Uuid(), time.Now().Add(time.Second*10)), "GET", "https://api.example.com/orders", "")
+	assert.NoError(t, err)
+
+	// A proof must carry its own public key and the dpop+jwt type.
+	ts, err := x1.Create(passport.NewClaims(userId1, time.Hour))
+	assert.NoError(t, err)
+	_, err = dpop.Verify(ctx, ts, "GET", "https://api.example.com/orders", "")
+	assert.ErrorIs(t, err, passport.ErrDPoPInvalid)
+
+	dpop.CheckNonce = func(ctx context.Context, nonce string) bool {
+		return nonce == "server-nonce"
+	}
+	proof, err = passport.NewDPoPProof(jwt.SigningMethodEdDSA, key, "GET", "https://api.example.com/orders", "")
+	assert.NoError(t, err)
+	_, err = dpop.Verify(ctx, proof, "GET", "https://api.example.com/orders", "")
+	assert.ErrorIs(t, err, passport.ErrDPoPInvalid)
+}
+
+func TestAuthenticateDPoP(t *testing.T) {
+	x := passport.New(
+		passport.SetIssuer("dev"),
+		passport.SetKey(key1),
+		passport.SetDPoP(passport.NewDPoP(rdb)),
+	)
+	engine := newAuthEngine(x)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	jwk, err := passport.NewJWK(passport.NewSigningKey("", jwt.SigningMethodES256, key))
+	assert.NoError(t, err)
+	jkt, err := jwk.Thumbprint()
+	assert.NoError(t, err)
+	ts, err := x.Create(passport.NewClaims(userId1, time.Hour).SetConfirmation(jkt))
+	assert.NoError(t, err)
+
+	htu := "http://api.example.com/users/1"
+	proof, err := passport.NewDPoPProof(jwt.SigningMethodES256, key, "GET", htu, ts)
+	assert.NoError(t, err)
+	headers := []ut.Header{
+		{Key: "X-Forwarded-Proto", Value: "http"},
+		{Key: "Authorization", Value: "DPoP " + ts},
+		{Key: "DPoP", Value: proof},
+	}
+	resp := ut.PerformRequest(engine, "GET", htu, nil, headers...).Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	var claims passport.Claims
+	assert.NoError(t, json.Unmarshal(resp.Body(), &claims))
+	assert.Equal(t, jkt, claims.Cnf.Jkt)
+
+	resp = ut.PerformRequest(engine, "GET", htu, nil, headers...).Result()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+	assert.JSONEq(t, `{"code":0,"message":"the proof of possession is invalid"}`, string(resp.Body()))
+
+	resp = ut.PerformRequest(engine, "GET", htu, nil, headers[:2]...).Result()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	proof, err = passport.NewDPoPProof(jwt.SigningMethodES256, other, "GET", htu, ts)
+	assert.NoError(t, err)
+	resp = ut.PerformRequest(engine, "GET", htu, nil, headers[0], headers[1],
+		ut.Header{Key: "DPoP", Value: proof}).Result()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+
+	// Bound tokens are refused where proofs cannot be checked.
+	proof, err = passport.NewDPoPProof(jwt.SigningMethodES256, key, "GET", htu, ts)
+	assert.NoError(t, err)
+	resp = ut.PerformRequest(newAuthEngine(x1), "GET", htu, nil, headers[0], headers[1],
+		ut.Header{Key: "DPoP", Value: proof}).Result()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+}
@@ -0,0 +1,49 @@
+package passport
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"github.com/weplanx/go/cipher"
+)
+
+// SetCipher seals the signed token with XChaCha20-Poly1305, so that the claims
+// stay confidential to the holder, Create and Verify keep the same API.
+func SetCipher(v *cipher.Cipher) Option {
+	return func(x *Passport) {
+		x.Cipher = v
+	}
+}
+
+var (
+	ErrDecryptToken = errors.New("the token cannot be decrypted")
+)
+
+// seal lays out nonce and ciphertext like cipher.Cipher.Encode,
+// but URL safe, the token also travels in cookies and query strings.
+func (x *Passport) seal(tokenString string) (_ string, err error) {
+	aead := x.Cipher.AEAD
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(tokenString)+aead.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return
+	}
+	encrypted := aead.Seal(nonce, nonce, []byte(tokenString), nil)
+	return base64.RawURLEncoding.EncodeToString(encrypted), nil
+}
+
+func (x *Passport) open(tokenString string) (_ string, err error) {
+	aead := x.Cipher.AEAD
+	var encrypted []byte
+	if encrypted, err = base64.RawURLEncoding.DecodeString(tokenString); err != nil {
+		return "", ErrDecryptToken
+	}
+	if len(encrypted) < aead.NonceSize() {
+		return "", ErrDecryptToken
+	}
+	nonce, text := encrypted[:aead.NonceSize()], encrypted[aead.NonceSize():]
+	var b []byte
+	if b, err = aead.Open(nil, nonce, text, nil); err != nil {
+		return "", ErrDecryptToken
+	}
+	return string(b), nil
+}
@@ -0,0 +1,55 @@
+package passport_test
+
+import (
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/cipher"
+	"github.com/weplanx/go/passport"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCipher(t *testing.T) {
+	c, err := cipher.New("6ixSiEXaqxsJTozbnxQ76CWdZXB2JazK")
+	assert.NoError(t, err)
+	x := passport.New(
+		passport.SetIssuer("dev"),
+		passport.SetKey(key1),
+		passport.SetCipher(c),
+	)
+	data := map[string]interface{}{"tenant": "secret-tenant", "role": "admin"}
+	ts, err := x.Create(passport.NewClaims(userId1, time.Hour).SetJTI(jti1).SetData(data))
+	assert.NoError(t, err)
+	assert.NotContains(t, ts, ".")
+	b, err := base64.RawURLEncoding.DecodeString(ts)
+	assert.NoError(t, err)
+	assert.False(t, strings.Contains(string(b), "secret-tenant"))
+
+	claims, err := x.Verify(ts)
+	assert.NoError(t, err)
+	assert.Equal(t, userId1, claims.ActiveId)
+	assert.Equal(t, "secret-tenant", claims.Data["tenant"])
+
+	users := passport.NewTyped[UserClaims](x)
+	ts, err = users.Create(&UserClaims{UserId: 1})
+	assert.NoError(t, err)
+	typed, err := users.Verify(ts)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), typed.UserId)
+
+	// Plain tokens are refused once the cipher is set.
+	plain, err := x1.Create(passport.NewClaims(userId1, time.Hour))
+	assert.NoError(t, err)
+	_, err = x.Verify(plain)
+	assert.ErrorIs(t, err, passport.ErrDecryptToken)
+	_, err = x1.Verify(ts)
+	assert.Error(t, err)
+
+	other, err := cipher.New("74rILbVooYLirHrQJcslHEAvKZI7PKF9")
+	assert.NoError(t, err)
+	_, err = passport.New(passport.SetKey(key1), passport.SetCipher(other)).Verify(ts)
+	assert.ErrorIs(t, err, passport.ErrDecryptToken)
+	_, err = x.Verify("abc")
+	assert.ErrorIs(t, err, passport.ErrDecryptToken)
+}
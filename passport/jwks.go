@@ -0,0 +1,382 @@
+package passport
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/emmansun/gmsm/sm2"
+	"github.com/golang-jwt/jwt/v5"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// KeySet looks up verification keys by kid, implemented by Keyring, JWKS and RemoteJWKS.
+type KeySet interface {
+	Get(kid string) (*SigningKey, bool)
+}
+
+// SetKeySet verifies tokens by kid with keys from outside the keyring, e.g. a JWKS endpoint.
+func SetKeySet(v KeySet) Option {
+	return func(x *Passport) {
+		x.KeySet = v
+	}
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+var (
+	ErrUnsupportedKey = errors.New("the key type is not supported by jwk")
+	ErrInvalidJWK     = errors.New("the jwk is invalid")
+)
+
+var b64 = base64.RawURLEncoding
+
+// NewJWK renders the public part of the key, HMAC secrets are never published.
+func NewJWK(key *SigningKey) (jwk JWK, err error) {
+	jwk = JWK{Kid: key.Kid, Use: "sig", Alg: key.Method.Alg()}
+	switch pub := key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = b64.EncodeToString(pub.N.Bytes())
+		jwk.E = b64.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		jwk.Kty = "EC"
+		switch {
+		case pub.Curve == elliptic.P256():
+			jwk.Crv = "P-256"
+		case sm2.IsSM2PublicKey(pub):
+			jwk.Crv = "SM2"
+		default:
+			return JWK{}, ErrUnsupportedKey
+		}
+		jwk.X = b64.EncodeToString(pub.X.FillBytes(make([]byte, 32)))
+		jwk.Y = b64.EncodeToString(pub.Y.FillBytes(make([]byte, 32)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = b64.EncodeToString(pub)
+	default:
+		return JWK{}, ErrUnsupportedKey
+	}
+	return
+}
+
+func (x JWK) PublicKey() (_ crypto.PublicKey, err error) {
+	switch x.Kty {
+	case "RSA":
+		var n, e []byte
+		if n, err = b64.DecodeString(x.N); err != nil {
+			return
+		}
+		if e, err = b64.DecodeString(x.E); err != nil {
+			return
+		}
+		if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, ErrInvalidJWK
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var px, py []byte
+		if px, err = b64.DecodeString(x.X); err != nil {
+			return
+		}
+		if py, err = b64.DecodeString(x.Y); err != nil {
+			return
+		}
+		if len(px) != 32 || len(py) != 32 {
+			return nil, ErrInvalidJWK
+		}
+		point := append(append([]byte{4}, px...), py...)
+		switch x.Crv {
+		case "P-256":
+			if _, err = ecdh.P256().NewPublicKey(point); err != nil {
+				return nil, ErrInvalidJWK
+			}
+			return &ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(px),
+				Y:     new(big.Int).SetBytes(py),
+			}, nil
+		case "SM2":
+			var pub *ecdsa.PublicKey
+			if pub, err = sm2.NewPublicKey(point); err != nil {
+				return nil, ErrInvalidJWK
+			}
+			return pub, nil
+		}
+	case "OKP":
+		if x.Crv != "Ed25519" {
+			break
+		}
+		var pub []byte
+		if pub, err = b64.DecodeString(x.X); err != nil {
+			return
+		}
+		if len(pub) != ed25519.PublicKeySize {
+			return nil, ErrInvalidJWK
+		}
+		return ed25519.PublicKey(pub), nil
+	}
+	return nil, ErrUnsupportedKey
+}
+
+// Thumbprint is the RFC 7638 JWK thumbprint with SHA-256.
+func (x JWK) Thumbprint() (_ string, err error) {
+	var members interface{}
+	switch x.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{x.E, x.Kty, x.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{x.Crv, x.Kty, x.X, x.Y}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{x.Crv, x.Kty, x.X}
+	default:
+		return "", ErrUnsupportedKey
+	}
+	var b []byte
+	if b, err = json.Marshal(members); err != nil {
+		return
+	}
+	h := sha256.Sum256(b)
+	return b64.EncodeToString(h[:]), nil
+}
+
+func (x JWK) SigningKey() (_ *SigningKey, err error) {
+	var pub crypto.PublicKey
+	if pub, err = x.PublicKey(); err != nil {
+		return
+	}
+	alg := x.Alg
+	if alg == "" {
+		switch x.Crv {
+		case "":
+			alg = jwt.SigningMethodRS256.Alg()
+		case "P-256":
+			alg = jwt.SigningMethodES256.Alg()
+		case "SM2":
+			alg = SigningMethodSM2.Alg()
+		case "Ed25519":
+			alg = jwt.SigningMethodEdDSA.Alg()
+		}
+	}
+	method := jwt.GetSigningMethod(alg)
+	if method == nil {
+		return nil, fmt.Errorf("unexpected signing method: %v", alg)
+	}
+	return NewVerifyKey(x.Kid, method, pub), nil
+}
+
+// JWKS renders the public keys of the keyring and the default key.
+func (x *Passport) JWKS() (jwks *JWKS, err error) {
+	jwks = &JWKS{Keys: []JWK{}}
+	keys := []*SigningKey{x.defaultKey()}
+	if x.Keyring != nil {
+		keys = append(keys, x.Keyring.Keys()...)
+	}
+	for _, v := range keys {
+		if v.IsHMAC() || v.PublicKey == nil {
+			continue
+		}
+		var jwk JWK
+		if jwk, err = NewJWK(v); err != nil {
+			return
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return
+}
+
+// JWKSHandler serves the public keys, e.g. at /.well-known/jwks.json
+func (x *Passport) JWKSHandler() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		jwks, err := x.JWKS()
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, jwks)
+	}
+}
+
+func ParseJWKS(r io.Reader) (jwks *JWKS, err error) {
+	jwks = new(JWKS)
+	if err = json.NewDecoder(r).Decode(jwks); err != nil {
+		return
+	}
+	return
+}
+
+func LoadJWKSFile(name string) (_ *JWKS, err error) {
+	var f *os.File
+	if f, err = os.Open(name); err != nil {
+		return
+	}
+	defer f.Close()
+	return ParseJWKS(f)
+}
+
+// Get skips keys that cannot be decoded, so that one bad entry does not break the rest.
+func (x *JWKS) Get(kid string) (*SigningKey, bool) {
+	for _, v := range x.Keys {
+		if v.Kid != kid || (v.Use != "" && v.Use != "sig") {
+			continue
+		}
+		key, err := v.SigningKey()
+		if err != nil {
+			continue
+		}
+		return key, true
+	}
+	return nil, false
+}
+
+// RemoteJWKS caches the JWKS of a URL, refetching once TTL passes
+// or when an unknown kid shows up, at most once per MinInterval, the failed attempts included.
+type RemoteJWKS struct {
+	URL         string
+	Client      *http.Client
+	TTL         time.Duration
+	MinInterval time.Duration
+
+	mu          sync.RWMutex
+	jwks        *JWKS
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	err         error
+	// refreshing collapses the concurrent refreshes of Get.
+	refreshing sync.Mutex
+}
+
+func NewRemoteJWKS(url string, ttl time.Duration) *RemoteJWKS {
+	return &RemoteJWKS{
+		URL:         url,
+		Client:      &http.Client{Timeout: time.Second * 10},
+		TTL:         ttl,
+		MinInterval: time.Second * 30,
+	}
+}
+
+// maxJWKSSize caps the response read by Refresh.
+const maxJWKSSize = 1 << 20
+
+func (x *RemoteJWKS) Refresh(ctx context.Context) (err error) {
+	x.mu.Lock()
+	x.attemptedAt = time.Now()
+	x.mu.Unlock()
+	defer func() {
+		x.mu.Lock()
+		x.err = err
+		x.mu.Unlock()
+	}()
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, x.URL, nil); err != nil {
+		return
+	}
+	var resp *http.Response
+	if resp, err = x.Client.Do(req); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected jwks response status: %d", resp.StatusCode)
+	}
+	var jwks *JWKS
+	if jwks, err = ParseJWKS(io.LimitReader(resp.Body, maxJWKSSize)); err != nil {
+		return
+	}
+	x.mu.Lock()
+	x.jwks = jwks
+	x.fetchedAt = time.Now()
+	x.mu.Unlock()
+	return
+}
+
+func (x *RemoteJWKS) Get(kid string) (*SigningKey, bool) {
+	x.mu.RLock()
+	jwks, age := x.jwks, time.Since(x.fetchedAt)
+	x.mu.RUnlock()
+	if jwks != nil && age < x.TTL {
+		if key, ok := jwks.Get(kid); ok {
+			return key, true
+		}
+	}
+	if x.due() {
+		x.refresh()
+		x.mu.RLock()
+		jwks = x.jwks
+		x.mu.RUnlock()
+	}
+	if jwks == nil {
+		return nil, false
+	}
+	return jwks.Get(kid)
+}
+
+// Err returns the error of the last refresh, nil when it succeeded, e.g. for health checks.
+func (x *RemoteJWKS) Err() error {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.err
+}
+
+// due reports whether MinInterval has passed since the last attempt.
+func (x *RemoteJWKS) due() bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.attemptedAt.IsZero() || time.Since(x.attemptedAt) >= x.MinInterval
+}
+
+// refresh lets one of the concurrent callers fetch, the others wait for it and reuse the result.
+func (x *RemoteJWKS) refresh() {
+	x.refreshing.Lock()
+	defer x.refreshing.Unlock()
+	if !x.due() {
+		return
+	}
+	x.Refresh(context.Background())
+}
@@ -0,0 +1,201 @@
+package passport_test
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+	"github.com/emmansun/gmsm/sm2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/passport"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newAsymmetricKeyring(t *testing.T) *passport.Keyring {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	sm2Key, err := sm2.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	return passport.NewKeyring(
+		passport.NewHMACKey("hs", "pjTxuJ6WvJYa0VqFmOv9aDJr8XgqHVFi"),
+		passport.NewSigningKey("rs", jwt.SigningMethodRS256, rsaKey),
+		passport.NewSigningKey("es", jwt.SigningMethodES256, ecdsaKey),
+		passport.NewSigningKey("ed", jwt.SigningMethodEdDSA, ed25519Key),
+		passport.NewSigningKey("sm", passport.SigningMethodSM2, sm2Key),
+	)
+}
+
+func TestJWKS(t *testing.T) {
+	keyring := newAsymmetricKeyring(t)
+	x := passport.New(passport.SetIssuer("dev"), passport.SetKeyring(keyring))
+	jwks, err := x.JWKS()
+	assert.NoError(t, err)
+	assert.Len(t, jwks.Keys, 4)
+	for _, v := range jwks.Keys {
+		assert.NotEqual(t, "hs", v.Kid)
+		key, ok := jwks.Get(v.Kid)
+		assert.True(t, ok)
+		original, _ := keyring.Get(v.Kid)
+		assert.Equal(t, original.Method.Alg(), key.Method.Alg())
+		assert.True(t, original.PublicKey.(interface{ Equal(x crypto.PublicKey) bool }).Equal(key.PublicKey))
+	}
+	_, ok := jwks.Get("hs")
+	assert.False(t, ok)
+
+	_, err = passport.NewJWK(passport.NewHMACKey("hs", "secret"))
+	assert.ErrorIs(t, err, passport.ErrUnsupportedKey)
+	_, err = passport.JWK{Kty: "EC", Crv: "P-256", X: "AA", Y: "AA"}.PublicKey()
+	assert.ErrorIs(t, err, passport.ErrInvalidJWK)
+	_, err = passport.JWK{Kty: "oct"}.PublicKey()
+	assert.ErrorIs(t, err, passport.ErrUnsupportedKey)
+}
+
+func TestJWKSHandler(t *testing.T) {
+	x := passport.New(passport.SetKeyring(newAsymmetricKeyring(t)))
+	engine := route.NewEngine(config.NewOptions([]config.Option{}))
+	engine.GET("/.well-known/jwks.json", x.JWKSHandler())
+	w := ut.PerformRequest(engine, "GET", "/.well-known/jwks.json", nil)
+	resp := w.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	jwks, err := passport.ParseJWKS(bytes.NewReader(resp.Body()))
+	assert.NoError(t, err)
+	assert.Len(t, jwks.Keys, 4)
+}
+
+func TestLoadJWKSFile(t *testing.T) {
+	keyring := newAsymmetricKeyring(t)
+	signer := passport.New(passport.SetIssuer("dev"), passport.SetKeyring(keyring))
+	jwks, err := signer.JWKS()
+	assert.NoError(t, err)
+	b, err := json.Marshal(jwks)
+	assert.NoError(t, err)
+	name := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(name, b, 0o600))
+
+	loaded, err := passport.LoadJWKSFile(name)
+	assert.NoError(t, err)
+	verifier := passport.New(passport.SetKeySet(loaded))
+	for _, kid := range []string{"rs", "es", "ed", "sm"} {
+		assert.NoError(t, keyring.Activate(kid))
+		ts, err := signer.Create(passport.NewClaims(userId1, time.Hour))
+		assert.NoError(t, err)
+		claims, err := verifier.Verify(ts)
+		assert.NoError(t, err)
+		assert.Equal(t, userId1, claims.ActiveId)
+	}
+	assert.NoError(t, keyring.Activate("hs"))
+	ts, err := signer.Create(passport.NewClaims(userId1, time.Hour))
+	assert.NoError(t, err)
+	_, err = verifier.Verify(ts)
+	assert.ErrorIs(t, err, passport.ErrKeyNotExists)
+
+	_, err = passport.LoadJWKSFile(filepath.Join(t.TempDir(), "none.json"))
+	assert.Error(t, err)
+}
+
+func TestRemoteJWKS(t *testing.T) {
+	keyring := newAsymmetricKeyring(t)
+	signer := passport.New(passport.SetIssuer("dev"), passport.SetKeyring(keyring))
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		jwks, err := signer.JWKS()
+		assert.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(jwks))
+	}))
+	defer server.Close()
+
+	remote := passport.NewRemoteJWKS(server.URL, time.Hour)
+	verifier := passport.New(passport.SetKeySet(remote))
+
+	ts, err := signer.Create(passport.NewClaims(userId1, time.Hour))
+	assert.NoError(t, err)
+	_, err = verifier.Verify(ts)
+	assert.NoError(t, err)
+	_, err = verifier.Verify(ts)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, requests)
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	keyring.Rotate(passport.NewSigningKey("es-2", jwt.SigningMethodES256, ecdsaKey))
+	ts, err = signer.Create(passport.NewClaims(userId1, time.Hour))
+	assert.NoError(t, err)
+
+	// The new kid is unknown, but the last fetch is within MinInterval.
+	_, err = verifier.Verify(ts)
+	assert.ErrorIs(t, err, passport.ErrKeyNotExists)
+	assert.Equal(t, 1, requests)
+
+	remote.MinInterval = 0
+	_, err = verifier.Verify(ts)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, requests)
+	assert.NoError(t, remote.Err())
+
+	failed := passport.NewRemoteJWKS("http://127.0.0.1:0", time.Hour)
+	_, ok := failed.Get("es-2")
+	assert.False(t, ok)
+	assert.Error(t, failed.Err())
+}
+
+func TestRemoteJWKSFailed(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		time.Sleep(time.Millisecond * 50)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	remote := passport.NewRemoteJWKS(server.URL, time.Hour)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, ok := remote.Get("es-1")
+			assert.False(t, ok)
+		}()
+	}
+	wg.Wait()
+	// the failed attempt is throttled by MinInterval as well
+	_, ok := remote.Get("es-1")
+	assert.False(t, ok)
+	assert.Equal(t, int64(1), requests.Load())
+	assert.ErrorContains(t, remote.Err(), "503")
+}
+
+func TestRemoteJWKSLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"keys":[],"padding":"`))
+		w.Write(bytes.Repeat([]byte("a"), 2<<20))
+		w.Write([]byte(`"}`))
+	}))
+	defer server.Close()
+
+	remote := passport.NewRemoteJWKS(server.URL, time.Hour)
+	assert.Error(t, remote.Refresh(context.TODO()))
+	assert.Error(t, remote.Err())
+}
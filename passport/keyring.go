@@ -0,0 +1,158 @@
+package passport
+
+import (
+	"crypto"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"sort"
+	"sync"
+)
+
+type SigningKey struct {
+	Kid        string
+	Method     jwt.SigningMethod
+	Secret     []byte
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+func NewHMACKey(kid string, secret string) *SigningKey {
+	return &SigningKey{
+		Kid:    kid,
+		Method: jwt.SigningMethodHS256,
+		Secret: []byte(secret),
+	}
+}
+
+func NewSigningKey(kid string, method jwt.SigningMethod, key crypto.Signer) *SigningKey {
+	return &SigningKey{
+		Kid:        kid,
+		Method:     method,
+		PrivateKey: key,
+		PublicKey:  key.Public(),
+	}
+}
+
+func NewVerifyKey(kid string, method jwt.SigningMethod, key crypto.PublicKey) *SigningKey {
+	return &SigningKey{
+		Kid:       kid,
+		Method:    method,
+		PublicKey: key,
+	}
+}
+
+func (x *SigningKey) IsHMAC() bool {
+	_, ok := x.Method.(*jwt.SigningMethodHMAC)
+	return ok
+}
+
+func (x *SigningKey) signKey() (interface{}, error) {
+	if x.IsHMAC() {
+		return x.Secret, nil
+	}
+	if x.PrivateKey == nil {
+		return nil, ErrMissingPrivateKey
+	}
+	return x.PrivateKey, nil
+}
+
+func (x *SigningKey) verifyKey(token *jwt.Token) (interface{}, error) {
+	if x.IsHMAC() {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || len(x.Secret) == 0 {
+			return nil, ErrUnexpectedMethod
+		}
+		return x.Secret, nil
+	}
+	if token.Method.Alg() != x.Method.Alg() || x.PublicKey == nil {
+		return nil, ErrUnexpectedMethod
+	}
+	return x.PublicKey, nil
+}
+
+var (
+	ErrUnexpectedMethod = errors.New("the signing method does not match the key")
+	ErrKeyNotExists     = errors.New("the key does not exists in the keyring")
+)
+
+// Keyring holds the keys for rotation, tokens are signed by the active key
+// and verified by the key named in the kid header until it is removed.
+type Keyring struct {
+	mu     sync.RWMutex
+	active string
+	keys   map[string]*SigningKey
+}
+
+// NewKeyring adds the keys and activates the last one.
+func NewKeyring(keys ...*SigningKey) *Keyring {
+	x := &Keyring{keys: make(map[string]*SigningKey)}
+	for _, v := range keys {
+		x.Rotate(v)
+	}
+	return x
+}
+
+func (x *Keyring) Add(key *SigningKey) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.keys[key.Kid] = key
+}
+
+func (x *Keyring) Activate(kid string) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if _, ok := x.keys[kid]; !ok {
+		return ErrKeyNotExists
+	}
+	x.active = kid
+	return nil
+}
+
+// Rotate adds the key and signs with it from now on, previous keys keep verifying.
+func (x *Keyring) Rotate(key *SigningKey) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.keys[key.Kid] = key
+	x.active = key.Kid
+}
+
+// Remove retires the key, tokens signed with it no longer verify.
+// The active key cannot be removed.
+func (x *Keyring) Remove(kid string) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if kid == x.active {
+		return false
+	}
+	if _, ok := x.keys[kid]; !ok {
+		return false
+	}
+	delete(x.keys, kid)
+	return true
+}
+
+func (x *Keyring) Active() *SigningKey {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.keys[x.active]
+}
+
+func (x *Keyring) Get(kid string) (*SigningKey, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	key, ok := x.keys[kid]
+	return key, ok
+}
+
+// Keys returns all keys sorted by kid.
+func (x *Keyring) Keys() []*SigningKey {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	keys := make([]*SigningKey, 0, len(x.keys))
+	for _, v := range x.keys {
+		keys = append(keys, v)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Kid < keys[j].Kid
+	})
+	return keys
+}
@@ -0,0 +1,85 @@
+package passport_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/passport"
+	"testing"
+	"time"
+)
+
+func TestKeyring(t *testing.T) {
+	keyring := passport.NewKeyring(
+		passport.NewHMACKey("2024", "pjTxuJ6WvJYa0VqFmOv9aDJr8XgqHVFi"),
+	)
+	x := passport.New(
+		passport.SetIssuer("dev"),
+		passport.SetKey(key1),
+		passport.SetKeyring(keyring),
+	)
+	oldToken, err := x.Create(passport.NewClaims(userId1, time.Hour*2).SetJTI(jti1))
+	assert.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(oldToken, &passport.Claims{})
+	assert.NoError(t, err)
+	assert.Equal(t, "2024", parsed.Header["kid"])
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	keyring.Rotate(passport.NewSigningKey("2025", jwt.SigningMethodES256, ecdsaKey))
+	newToken, err := x.Create(passport.NewClaims(userId2, time.Hour*2).SetJTI(jti2))
+	assert.NoError(t, err)
+	parsed, _, err = jwt.NewParser().ParseUnverified(newToken, &passport.Claims{})
+	assert.NoError(t, err)
+	assert.Equal(t, "2025", parsed.Header["kid"])
+	assert.Equal(t, "ES256", parsed.Method.Alg())
+
+	claims, err := x.Verify(oldToken)
+	assert.NoError(t, err)
+	assert.Equal(t, userId1, claims.ActiveId)
+	claims, err = x.Verify(newToken)
+	assert.NoError(t, err)
+	assert.Equal(t, userId2, claims.ActiveId)
+
+	// Tokens issued before the keyring existed carry no kid.
+	legacyToken, err := passport.New(passport.SetKey(key1)).Create(passport.NewClaims(userId1, time.Hour))
+	assert.NoError(t, err)
+	claims, err = x.Verify(legacyToken)
+	assert.NoError(t, err)
+	assert.Equal(t, userId1, claims.ActiveId)
+
+	assert.False(t, keyring.Remove("2025"))
+	assert.True(t, keyring.Remove("2024"))
+	_, err = x.Verify(oldToken)
+	assert.ErrorIs(t, err, passport.ErrKeyNotExists)
+	_, err = x.Verify(newToken)
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, keyring.Activate("2024"), passport.ErrKeyNotExists)
+	assert.Len(t, keyring.Keys(), 1)
+}
+
+func TestKeyringUnexpectedMethod(t *testing.T) {
+	keyring := passport.NewKeyring(
+		passport.NewHMACKey("v1", "pjTxuJ6WvJYa0VqFmOv9aDJr8XgqHVFi"),
+	)
+	x := passport.New(passport.SetKeyring(keyring))
+
+	ecdsaKey, err := jwt.ParseECPrivateKeyFromPEM([]byte(ecPKey))
+	assert.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodES256, passport.NewClaims(userId1, time.Hour))
+	forged.Header["kid"] = "v1"
+	ts, err := forged.SignedString(ecdsaKey)
+	assert.NoError(t, err)
+	_, err = x.Verify(ts)
+	assert.ErrorIs(t, err, passport.ErrUnexpectedMethod)
+
+	// Without a default key, tokens lacking kid are refused.
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodHS256, passport.NewClaims(userId1, time.Hour)).
+		SignedString([]byte(""))
+	assert.NoError(t, err)
+	_, err = x.Verify(unsigned)
+	assert.ErrorIs(t, err, passport.ErrUnexpectedMethod)
+}
@@ -1,18 +1,46 @@
 package passport
 
 import (
-	"fmt"
+	"context"
+	"crypto"
+	"errors"
+	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/weplanx/go/cipher"
 	"time"
 )
 
 type Passport struct {
-	Issuer string
-	Key    string
+	Issuer     string
+	Key        string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+	Keyring    *Keyring
+	KeySet     KeySet
+	Denylist   Denylist
+	Extractors []Extractor
+	Skipper    func(ctx context.Context, c *app.RequestContext) bool
+	Cipher     *cipher.Cipher
+	DPoP       *DPoP
+	Sessions   *Sessions
+
+	Audience       []string
+	IssueAudience  []string
+	StrictIssuer   bool
+	Leeway         time.Duration
+	MaxAge         time.Duration
+	RequiredClaims []string
 }
 
 func New(options ...Option) *Passport {
-	x := new(Passport)
+	x := &Passport{
+		Method: jwt.SigningMethodHS256,
+		Extractors: []Extractor{
+			FromHeader("Authorization"),
+			FromCookie("access_token"),
+		},
+	}
 	for _, v := range options {
 		v(x)
 	}
@@ -33,9 +61,37 @@ func SetKey(v string) Option {
 	}
 }
 
+// SetSigningKey signs with an asymmetric algorithm,
+// e.g. RS256 with *rsa.PrivateKey, ES256 with *ecdsa.PrivateKey,
+// EdDSA with ed25519.PrivateKey or SM2 with *sm2.PrivateKey.
+func SetSigningKey(method jwt.SigningMethod, key crypto.Signer) Option {
+	return func(x *Passport) {
+		x.Method = method
+		x.PrivateKey = key
+		x.PublicKey = key.Public()
+	}
+}
+
+// SetVerifyKey only verifies tokens, the private key stays with the issuing service.
+func SetVerifyKey(method jwt.SigningMethod, key crypto.PublicKey) Option {
+	return func(x *Passport) {
+		x.Method = method
+		x.PublicKey = key
+	}
+}
+
+// SetKeyring signs with the active key of the keyring and
+// verifies by the kid header, tokens without kid fall back to the key above.
+func SetKeyring(v *Keyring) Option {
+	return func(x *Passport) {
+		x.Keyring = v
+	}
+}
+
 type Claims struct {
 	ActiveId string
 	Data     map[string]interface{}
+	Cnf      *Confirmation `json:"cnf,omitempty"`
 
 	jwt.RegisteredClaims
 }
@@ -61,25 +117,86 @@ func (x *Claims) SetIssuer(v string) *Claims {
 	return x
 }
 
+func (x *Claims) SetAudience(v ...string) *Claims {
+	x.Audience = v
+	return x
+}
+
 func (x *Claims) SetData(v map[string]interface{}) *Claims {
 	x.Data = v
 	return x
 }
 
+var (
+	ErrMissingPrivateKey = errors.New("the private key is not set, only verification is available")
+)
+
 func (x *Passport) Create(claims *Claims) (tokenString string, err error) {
 	claims.SetIssuer(x.Issuer)
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(x.Key))
+	return x.sign(claims)
+}
+
+func (x *Passport) sign(claims jwt.Claims) (tokenString string, err error) {
+	if r := registered(claims); r != nil && len(r.Audience) == 0 && len(x.IssueAudience) != 0 {
+		r.Audience = x.IssueAudience
+	}
+	key := x.signingKey()
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.Kid != "" {
+		token.Header["kid"] = key.Kid
+	}
+	var k interface{}
+	if k, err = key.signKey(); err != nil {
+		return
+	}
+	if tokenString, err = token.SignedString(k); err != nil {
+		return
+	}
+	if x.Cipher != nil {
+		return x.seal(tokenString)
+	}
+	return
 }
 
 func (x *Passport) Verify(tokenString string) (claims Claims, err error) {
-	if _, err = jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(x.Key), nil
-	}); err != nil {
+	if err = x.parse(tokenString, &claims); err != nil {
 		return
 	}
 	return
 }
+
+func (x *Passport) signingKey() *SigningKey {
+	if x.Keyring != nil {
+		if key := x.Keyring.Active(); key != nil {
+			return key
+		}
+	}
+	return x.defaultKey()
+}
+
+// defaultKey is the key set by SetKey, SetSigningKey or SetVerifyKey.
+func (x *Passport) defaultKey() *SigningKey {
+	return &SigningKey{
+		Method:     x.Method,
+		Secret:     []byte(x.Key),
+		PrivateKey: x.PrivateKey,
+		PublicKey:  x.PublicKey,
+	}
+}
+
+func (x *Passport) keyFunc(token *jwt.Token) (interface{}, error) {
+	if kid, ok := token.Header["kid"].(string); ok && (x.Keyring != nil || x.KeySet != nil) {
+		if x.Keyring != nil {
+			if key, exists := x.Keyring.Get(kid); exists {
+				return key.verifyKey(token)
+			}
+		}
+		if x.KeySet != nil {
+			if key, exists := x.KeySet.Get(kid); exists {
+				return key.verifyKey(token)
+			}
+		}
+		return nil, ErrKeyNotExists
+	}
+	return x.defaultKey().verifyKey(token)
+}
@@ -1,9 +1,17 @@
 package passport_test
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/weplanx/go/passport"
+	"log"
 	"os"
 	"testing"
 	"time"
@@ -11,10 +19,16 @@ import (
 
 var x1 *passport.Passport
 var x2 *passport.Passport
+var rdb *redis.Client
 
 var key1 = "hZXD^@K9%wydDC3Z@cyDvE%5bz9SP7gy"
 
 func TestMain(m *testing.M) {
+	opts, err := redis.ParseURL(os.Getenv("DATABASE_REDIS"))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	rdb = redis.NewClient(opts)
 	x1 = passport.New(
 		passport.SetIssuer("dev"),
 		passport.SetKey(key1),
@@ -106,3 +120,43 @@ func TestOtherSigningMethod(t *testing.T) {
 	assert.Error(t, err)
 	t.Log(err)
 }
+
+func TestAsymmetricSigningMethod(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	for _, v := range []struct {
+		method jwt.SigningMethod
+		key    crypto.Signer
+	}{
+		{jwt.SigningMethodRS256, rsaKey},
+		{jwt.SigningMethodES256, ecdsaKey},
+		{jwt.SigningMethodEdDSA, ed25519Key},
+	} {
+		signer := passport.New(
+			passport.SetIssuer("dev"),
+			passport.SetSigningKey(v.method, v.key),
+		)
+		verifier := passport.New(
+			passport.SetIssuer("dev"),
+			passport.SetVerifyKey(v.method, v.key.Public()),
+		)
+		ts, err := signer.Create(passport.NewClaims(userId1, time.Hour*2).SetJTI(jti1))
+		assert.NoError(t, err)
+		var claims passport.Claims
+		claims, err = verifier.Verify(ts)
+		assert.NoError(t, err)
+		assert.Equal(t, userId1, claims.ActiveId)
+
+		_, err = verifier.Create(passport.NewClaims(userId1, time.Hour*2))
+		assert.ErrorIs(t, err, passport.ErrMissingPrivateKey)
+		_, err = x1.Verify(ts)
+		assert.Error(t, err)
+		_, err = verifier.Verify(token)
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,140 @@
+package passport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/weplanx/go/help"
+	"strings"
+	"time"
+)
+
+// Refresh issues access/refresh token pairs, every refresh token belongs to a family
+// and can be exchanged once, presenting it again revokes the whole family.
+type Refresh struct {
+	Passport   *Passport
+	RDb        *redis.Client
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+func NewRefresh(passport *Passport, rdb *redis.Client) *Refresh {
+	return &Refresh{
+		Passport:   passport,
+		RDb:        rdb,
+		AccessTTL:  time.Minute * 15,
+		RefreshTTL: time.Hour * 24 * 7,
+	}
+}
+
+// Key is the refresh token by its hash, in the cluster slot of the family.
+func (x *Refresh) Key(family string, hash string) string {
+	return fmt.Sprintf(`passport:refresh:{%s}:%s`, family, hash)
+}
+
+func (x *Refresh) FamilyKey(name string) string {
+	return fmt.Sprintf(`passport:family:{%s}`, name)
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	Family       string `json:"-"`
+}
+
+var (
+	ErrRefreshNotExists = errors.New("the refresh token does not exists")
+	ErrRefreshRevoked   = errors.New("the refresh token family has been revoked")
+	ErrRefreshReused    = errors.New("the refresh token has been reused, the family is revoked")
+)
+
+// Issue starts a new family, e.g. on login.
+func (x *Refresh) Issue(ctx context.Context, activeId string, data map[string]interface{}) (pair TokenPair, err error) {
+	family := help.Uuid()
+	if err = x.RDb.Set(ctx, x.FamilyKey(family), activeId, x.RefreshTTL).Err(); err != nil {
+		return
+	}
+	return x.issue(ctx, family, activeId, data)
+}
+
+func (x *Refresh) issue(ctx context.Context, family string, activeId string, data map[string]interface{}) (pair TokenPair, err error) {
+	pair.Family = family
+	claims := NewClaims(activeId, x.AccessTTL).SetJTI(help.Uuid()).SetData(data)
+	if pair.AccessToken, err = x.Passport.Create(claims); err != nil {
+		return
+	}
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return
+	}
+	// the family prefixes the token to find its slot
+	pair.RefreshToken = family + "." + base64.RawURLEncoding.EncodeToString(b)
+	pair.ExpiresIn = int64(x.AccessTTL.Seconds())
+
+	var raw []byte
+	if raw, err = json.Marshal(data); err != nil {
+		return
+	}
+	key := x.Key(family, help.Sha256hex(pair.RefreshToken))
+	if _, err = x.RDb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HSet(ctx, key, "family", family, "active_id", activeId, "data", raw, "used", 0)
+		p.Expire(ctx, key, x.RefreshTTL)
+		p.Expire(ctx, x.FamilyKey(family), x.RefreshTTL)
+		return nil
+	}); err != nil {
+		return
+	}
+	return
+}
+
+var exchangeScript = redis.NewScript(`
+local r = redis.call('HMGET', KEYS[1], 'family', 'active_id', 'data', 'used')
+if not r[1] then
+	return {0}
+end
+if redis.call('EXISTS', KEYS[2]) == 0 then
+	return {1}
+end
+if r[4] == '1' then
+	redis.call('DEL', KEYS[2])
+	return {2}
+end
+redis.call('HSET', KEYS[1], 'used', 1)
+return {3, r[1], r[2], r[3]}
+`)
+
+// Exchange consumes the refresh token and returns a new pair of the same family.
+func (x *Refresh) Exchange(ctx context.Context, refreshToken string) (pair TokenPair, err error) {
+	family, _, ok := strings.Cut(refreshToken, ".")
+	if !ok {
+		return pair, ErrRefreshNotExists
+	}
+	keys := []string{x.Key(family, help.Sha256hex(refreshToken)), x.FamilyKey(family)}
+	var r []interface{}
+	if r, err = exchangeScript.Run(ctx, x.RDb, keys).Slice(); err != nil {
+		return
+	}
+	switch r[0].(int64) {
+	case 0:
+		return pair, ErrRefreshNotExists
+	case 1:
+		return pair, ErrRefreshRevoked
+	case 2:
+		return pair, ErrRefreshReused
+	}
+	var data map[string]interface{}
+	if err = json.Unmarshal([]byte(r[3].(string)), &data); err != nil {
+		return
+	}
+	return x.issue(ctx, r[1].(string), r[2].(string), data)
+}
+
+// RevokeFamily invalidates every refresh token of the family, e.g. on logout.
+func (x *Refresh) RevokeFamily(ctx context.Context, family string) error {
+	return x.RDb.Del(ctx, x.FamilyKey(family)).Err()
+}
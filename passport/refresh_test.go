@@ -0,0 +1,82 @@
+package passport_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/help"
+	"github.com/weplanx/go/passport"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRefresh(t *testing.T) {
+	ctx := context.TODO()
+	r := passport.NewRefresh(x1, rdb)
+	activeId := help.Uuid()
+	pair1, err := r.Issue(ctx, activeId, map[string]interface{}{"tenant": "t1"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, pair1.RefreshToken)
+	assert.Equal(t, int64(900), pair1.ExpiresIn)
+	claims, err := x1.Verify(pair1.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, activeId, claims.ActiveId)
+
+	pair2, err := r.Exchange(ctx, pair1.RefreshToken)
+	assert.NoError(t, err)
+	assert.Equal(t, pair1.Family, pair2.Family)
+	assert.NotEqual(t, pair1.RefreshToken, pair2.RefreshToken)
+	claims, err = x1.Verify(pair2.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, activeId, claims.ActiveId)
+	assert.Equal(t, "t1", claims.Data["tenant"])
+
+	pair3, err := r.Exchange(ctx, pair2.RefreshToken)
+	assert.NoError(t, err)
+
+	// A stolen token replayed revokes the family, including the latest token.
+	_, err = r.Exchange(ctx, pair1.RefreshToken)
+	assert.ErrorIs(t, err, passport.ErrRefreshReused)
+	_, err = r.Exchange(ctx, pair3.RefreshToken)
+	assert.ErrorIs(t, err, passport.ErrRefreshRevoked)
+
+	_, err = r.Exchange(ctx, "unknown")
+	assert.ErrorIs(t, err, passport.ErrRefreshNotExists)
+}
+
+func TestRefreshKeys(t *testing.T) {
+	ctx := context.TODO()
+	r := passport.NewRefresh(x1, rdb)
+	pair, err := r.Issue(ctx, help.Uuid(), nil)
+	assert.NoError(t, err)
+	// the keys of the script share the slot of the family
+	assert.True(t, strings.HasPrefix(pair.RefreshToken, pair.Family+"."))
+	assert.Contains(t, r.Key(pair.Family, help.Sha256hex(pair.RefreshToken)), "{"+pair.Family+"}")
+	assert.Contains(t, r.FamilyKey(pair.Family), "{"+pair.Family+"}")
+
+	// moved to another family, the token is unknown
+	other, err := r.Issue(ctx, help.Uuid(), nil)
+	assert.NoError(t, err)
+	_, secret, _ := strings.Cut(pair.RefreshToken, ".")
+	_, err = r.Exchange(ctx, other.Family+"."+secret)
+	assert.ErrorIs(t, err, passport.ErrRefreshNotExists)
+	_, err = r.Exchange(ctx, pair.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestRefreshRevokeFamily(t *testing.T) {
+	ctx := context.TODO()
+	r := passport.NewRefresh(x1, rdb)
+	r.RefreshTTL = time.Second
+	pair, err := r.Issue(ctx, help.Uuid(), nil)
+	assert.NoError(t, err)
+	assert.NoError(t, r.RevokeFamily(ctx, pair.Family))
+	_, err = r.Exchange(ctx, pair.RefreshToken)
+	assert.ErrorIs(t, err, passport.ErrRefreshRevoked)
+
+	pair, err = r.Issue(ctx, help.Uuid(), nil)
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 1500)
+	_, err = r.Exchange(ctx, pair.RefreshToken)
+	assert.ErrorIs(t, err, passport.ErrRefreshNotExists)
+}
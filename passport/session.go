@@ -0,0 +1,268 @@
+package passport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+type Session struct {
+	JTI       string    `json:"jti"`
+	ActiveId  string    `json:"active_id"`
+	Device    string    `json:"device"`
+	UserAgent string    `json:"user_agent"`
+	IP        string    `json:"ip"`
+	IssuedAt  time.Time `json:"issued_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewSession describes the token issued for the request, Device is left to the caller.
+func NewSession(claims *Claims, c *app.RequestContext) Session {
+	s := Session{
+		JTI:      claims.ID,
+		ActiveId: claims.ActiveId,
+		LastSeen: time.Now(),
+	}
+	if claims.IssuedAt != nil {
+		s.IssuedAt = claims.IssuedAt.Time
+	}
+	if claims.ExpiresAt != nil {
+		s.ExpiresAt = claims.ExpiresAt.Time
+	}
+	if c != nil {
+		s.UserAgent = string(c.UserAgent())
+		s.IP = c.ClientIP()
+	}
+	return s
+}
+
+// SetSessions makes Authenticate accept only tokens with a live session and track last seen.
+func SetSessions(v *Sessions) Option {
+	return func(x *Passport) {
+		x.Sessions = v
+	}
+}
+
+// Sessions records the issued tokens per ActiveId,
+// the keys of an ActiveId are tagged to share a cluster slot.
+type Sessions struct {
+	RDb *redis.Client
+	// Max caps the concurrent sessions of an ActiveId, the oldest are evicted, 0 is unlimited.
+	Max int64
+	// Denylist is optional, revoked and evicted sessions are denied there too.
+	Denylist Denylist
+}
+
+func NewSessions(rdb *redis.Client, max int64) *Sessions {
+	return &Sessions{RDb: rdb, Max: max}
+}
+
+// Key is the hash of the sessions of activeId by jti.
+func (x *Sessions) Key(activeId string) string {
+	return fmt.Sprintf(`passport:sessions:{%s}`, activeId)
+}
+
+// IssuedKey orders the sessions by issued at.
+func (x *Sessions) IssuedKey(activeId string) string {
+	return fmt.Sprintf(`passport:sessions:{%s}:issued`, activeId)
+}
+
+// ExpiresKey orders the sessions by expires at.
+func (x *Sessions) ExpiresKey(activeId string) string {
+	return fmt.Sprintf(`passport:sessions:{%s}:expires`, activeId)
+}
+
+func (x *Sessions) keys(activeId string) []string {
+	return []string{x.Key(activeId), x.IssuedKey(activeId), x.ExpiresKey(activeId)}
+}
+
+var (
+	ErrSessionNotExists = errors.New("the session does not exists")
+)
+
+var recordScript = redis.NewScript(`
+for _, m in ipairs(redis.call('ZRANGEBYSCORE', KEYS[3], '-inf', ARGV[4])) do
+	redis.call('HDEL', KEYS[1], m)
+	redis.call('ZREM', KEYS[2], m)
+end
+redis.call('ZREMRANGEBYSCORE', KEYS[3], '-inf', ARGV[4])
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[6])
+redis.call('ZADD', KEYS[2], ARGV[2], ARGV[1])
+redis.call('ZADD', KEYS[3], ARGV[3], ARGV[1])
+local evicted = {}
+local n = redis.call('ZCARD', KEYS[2]) - tonumber(ARGV[5])
+if tonumber(ARGV[5]) > 0 and n > 0 then
+	for _, m in ipairs(redis.call('ZRANGE', KEYS[2], 0, n - 1)) do
+		table.insert(evicted, redis.call('HGET', KEYS[1], m))
+		redis.call('HDEL', KEYS[1], m)
+		redis.call('ZREM', KEYS[3], m)
+	end
+	redis.call('ZREMRANGEBYRANK', KEYS[2], 0, n - 1)
+end
+local last = redis.call('ZRANGE', KEYS[3], -1, -1, 'WITHSCORES')
+for _, k in ipairs(KEYS) do
+	redis.call('PEXPIREAT', k, last[2])
+end
+return evicted
+`)
+
+// Record stores the session until the token expires, and returns the sessions evicted by Max.
+func (x *Sessions) Record(ctx context.Context, s Session) (evicted []Session, err error) {
+	if s.JTI == "" {
+		return nil, ErrMissingJTI
+	}
+	if !s.ExpiresAt.After(time.Now()) {
+		return nil, ErrTokenExpired
+	}
+	var b []byte
+	if b, err = json.Marshal(s); err != nil {
+		return
+	}
+	var r []interface{}
+	if r, err = recordScript.Run(ctx, x.RDb, x.keys(s.ActiveId),
+		s.JTI, s.IssuedAt.UnixMilli(), s.ExpiresAt.UnixMilli(), time.Now().UnixMilli(), x.Max, b,
+	).Slice(); err != nil {
+		return
+	}
+	for _, v := range r {
+		var e Session
+		if err = json.Unmarshal([]byte(v.(string)), &e); err != nil {
+			return
+		}
+		if err = x.deny(ctx, e); err != nil {
+			return
+		}
+		evicted = append(evicted, e)
+	}
+	return
+}
+
+// decode drops the session expired but not yet pruned by Record.
+func decode(v string) (s Session, ok bool, err error) {
+	if err = json.Unmarshal([]byte(v), &s); err != nil {
+		return
+	}
+	return s, s.ExpiresAt.After(time.Now()), nil
+}
+
+func (x *Sessions) Get(ctx context.Context, activeId string, jti string) (s Session, err error) {
+	var v string
+	if v, err = x.RDb.HGet(ctx, x.Key(activeId), jti).Result(); err != nil {
+		if errors.Is(err, redis.Nil) {
+			err = ErrSessionNotExists
+		}
+		return
+	}
+	var ok bool
+	if s, ok, err = decode(v); err == nil && !ok {
+		err = ErrSessionNotExists
+	}
+	return
+}
+
+var touchScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
+// Touch updates last seen and the IP, when ip is not empty.
+func (x *Sessions) Touch(ctx context.Context, activeId string, jti string, ip string) (err error) {
+	var s Session
+	if s, err = x.Get(ctx, activeId, jti); err != nil {
+		return
+	}
+	s.LastSeen = time.Now()
+	if ip != "" {
+		s.IP = ip
+	}
+	var b []byte
+	if b, err = json.Marshal(s); err != nil {
+		return
+	}
+	// a session revoked meanwhile must not come back
+	var n int64
+	if n, err = touchScript.Run(ctx, x.RDb, []string{x.Key(activeId)}, jti, b).Int64(); err != nil {
+		return
+	}
+	if n == 0 {
+		return ErrSessionNotExists
+	}
+	return
+}
+
+// List returns the live sessions of activeId, oldest first.
+func (x *Sessions) List(ctx context.Context, activeId string) (sessions []Session, err error) {
+	var jtis []string
+	if jtis, err = x.RDb.ZRange(ctx, x.IssuedKey(activeId), 0, -1).Result(); err != nil {
+		return
+	}
+	sessions = make([]Session, 0, len(jtis))
+	if len(jtis) == 0 {
+		return
+	}
+	var values []interface{}
+	if values, err = x.RDb.HMGet(ctx, x.Key(activeId), jtis...).Result(); err != nil {
+		return
+	}
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		var s Session
+		var ok bool
+		if s, ok, err = decode(v.(string)); err != nil {
+			return
+		}
+		if ok {
+			sessions = append(sessions, s)
+		}
+	}
+	return
+}
+
+// Revoke kills one session, e.g. "log out this device".
+func (x *Sessions) Revoke(ctx context.Context, activeId string, jti string) (err error) {
+	var s Session
+	if s, err = x.Get(ctx, activeId, jti); err != nil {
+		return
+	}
+	if err = x.deny(ctx, s); err != nil {
+		return
+	}
+	_, err = x.RDb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HDel(ctx, x.Key(activeId), jti)
+		p.ZRem(ctx, x.IssuedKey(activeId), jti)
+		p.ZRem(ctx, x.ExpiresKey(activeId), jti)
+		return nil
+	})
+	return
+}
+
+// RevokeAll kills every session of activeId.
+func (x *Sessions) RevokeAll(ctx context.Context, activeId string) (err error) {
+	var sessions []Session
+	if sessions, err = x.List(ctx, activeId); err != nil {
+		return
+	}
+	for _, s := range sessions {
+		if err = x.deny(ctx, s); err != nil {
+			return
+		}
+	}
+	return x.RDb.Del(ctx, x.keys(activeId)...).Err()
+}
+
+func (x *Sessions) deny(ctx context.Context, s Session) error {
+	if x.Denylist == nil {
+		return nil
+	}
+	return x.Denylist.Revoke(ctx, s.JTI, s.ExpiresAt)
+}
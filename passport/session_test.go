@@ -0,0 +1,163 @@
+package passport_test
+
+import (
+	"context"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/help"
+	"github.com/weplanx/go/passport"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSessions(t *testing.T) {
+	ctx := context.TODO()
+	denylist := passport.NewMemoryDenylist()
+	sessions := passport.NewSessions(rdb, 3)
+	sessions.Denylist = denylist
+	x := passport.New(
+		passport.SetIssuer("dev"),
+		passport.SetKey(key1),
+		passport.SetDenylist(denylist),
+	)
+	activeId := help.Uuid()
+	tokens := make([]string, 4)
+	for i := range tokens {
+		claims := passport.NewClaims(activeId, time.Hour).SetJTI(help.Uuid())
+		claims.IssuedAt.Time = claims.IssuedAt.Add(time.Duration(i) * time.Millisecond)
+		var err error
+		tokens[i], err = x.Create(claims)
+		assert.NoError(t, err)
+		s := passport.NewSession(claims, nil)
+		s.Device = "iPhone"
+		evicted, err := sessions.Record(ctx, s)
+		assert.NoError(t, err)
+		if i < 3 {
+			assert.Empty(t, evicted)
+		} else {
+			assert.Len(t, evicted, 1)
+		}
+	}
+
+	list, err := sessions.List(ctx, activeId)
+	assert.NoError(t, err)
+	assert.Len(t, list, 3)
+	assert.Equal(t, "iPhone", list[0].Device)
+
+	// The oldest was evicted and its token is denied.
+	_, err = x.VerifyContext(ctx, tokens[0])
+	assert.ErrorIs(t, err, passport.ErrTokenRevoked)
+
+	assert.NoError(t, sessions.Touch(ctx, activeId, list[0].JTI, "10.0.0.1"))
+	s, err := sessions.Get(ctx, activeId, list[0].JTI)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", s.IP)
+	assert.True(t, s.LastSeen.After(list[0].LastSeen))
+
+	assert.ErrorIs(t, sessions.Revoke(ctx, "other", list[0].JTI), passport.ErrSessionNotExists)
+	assert.NoError(t, sessions.Revoke(ctx, activeId, list[0].JTI))
+	assert.ErrorIs(t, sessions.Touch(ctx, activeId, list[0].JTI, ""), passport.ErrSessionNotExists)
+	_, err = x.VerifyContext(ctx, tokens[1])
+	assert.ErrorIs(t, err, passport.ErrTokenRevoked)
+	list, err = sessions.List(ctx, activeId)
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	assert.NoError(t, sessions.RevokeAll(ctx, activeId))
+	list, err = sessions.List(ctx, activeId)
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+	_, err = x.VerifyContext(ctx, tokens[3])
+	assert.ErrorIs(t, err, passport.ErrTokenRevoked)
+
+	_, err = sessions.Record(ctx, passport.Session{ActiveId: activeId})
+	assert.ErrorIs(t, err, passport.ErrMissingJTI)
+	_, err = sessions.Record(ctx, passport.Session{ActiveId: activeId, JTI: "expired"})
+	assert.ErrorIs(t, err, passport.ErrTokenExpired)
+}
+
+func TestAuthenticateSessions(t *testing.T) {
+	ctx := context.TODO()
+	sessions := passport.NewSessions(rdb, 0)
+	x := passport.New(
+		passport.SetIssuer("dev"),
+		passport.SetKey(key1),
+		passport.SetSessions(sessions),
+	)
+	engine := newAuthEngine(x)
+	activeId := help.Uuid()
+	claims := passport.NewClaims(activeId, time.Hour).SetJTI(help.Uuid())
+	ts, err := x.Create(claims)
+	assert.NoError(t, err)
+	header := ut.Header{Key: "Authorization", Value: "Bearer " + ts}
+
+	resp := ut.PerformRequest(engine, "GET", "/users/1", nil, header).Result()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+
+	_, err = sessions.Record(ctx, passport.NewSession(claims, nil))
+	assert.NoError(t, err)
+	resp = ut.PerformRequest(engine, "GET", "/users/1", nil, header).Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+
+	assert.NoError(t, sessions.Revoke(ctx, activeId, claims.ID))
+	resp = ut.PerformRequest(engine, "GET", "/users/1", nil, header).Result()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+	assert.JSONEq(t, `{"code":0,"message":"the token is invalid"}`, string(resp.Body()))
+}
+
+func TestSessionsTouchRevoked(t *testing.T) {
+	ctx := context.TODO()
+	sessions := passport.NewSessions(rdb, 3)
+	activeId := help.Uuid()
+	for i := 0; i < 20; i++ {
+		claims := passport.NewClaims(activeId, time.Hour).SetJTI(help.Uuid())
+		_, err := sessions.Record(ctx, passport.NewSession(claims, nil))
+		assert.NoError(t, err)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			sessions.Touch(ctx, activeId, claims.ID, "10.0.0.1")
+		}()
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, sessions.Revoke(ctx, activeId, claims.ID))
+		}()
+		wg.Wait()
+		// Touch racing with Revoke must not bring the session back
+		ok, err := rdb.HExists(ctx, sessions.Key(activeId), claims.ID).Result()
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	}
+}
+
+func TestSessionsExpired(t *testing.T) {
+	ctx := context.TODO()
+	sessions := passport.NewSessions(rdb, 0)
+	activeId := help.Uuid()
+	short := passport.Session{JTI: help.Uuid(), ActiveId: activeId, ExpiresAt: time.Now().Add(time.Millisecond * 500)}
+	_, err := sessions.Record(ctx, short)
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 600)
+	_, err = sessions.Get(ctx, activeId, short.JTI)
+	assert.ErrorIs(t, err, passport.ErrSessionNotExists)
+	list, err := sessions.List(ctx, activeId)
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+
+	long := passport.NewClaims(activeId, time.Hour).SetJTI(help.Uuid())
+	_, err = sessions.Record(ctx, passport.NewSession(long, nil))
+	assert.NoError(t, err)
+	// the expired session is pruned, the keys live as long as the last one
+	n, err := rdb.HLen(ctx, sessions.Key(activeId)).Result()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	for _, key := range []string{sessions.Key(activeId), sessions.IssuedKey(activeId), sessions.ExpiresKey(activeId)} {
+		ttl, err := rdb.PTTL(ctx, key).Result()
+		assert.NoError(t, err)
+		assert.Greater(t, ttl, time.Minute*59)
+	}
+	assert.NoError(t, sessions.RevokeAll(ctx, activeId))
+}
@@ -0,0 +1,42 @@
+package passport
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"github.com/emmansun/gmsm/sm2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningMethodSM2 signs with SM2 over an SM3 digest using the default UID (GB/T 32918.2-2016).
+var SigningMethodSM2 = new(signingMethodSM2)
+
+type signingMethodSM2 struct{}
+
+func init() {
+	jwt.RegisterSigningMethod(SigningMethodSM2.Alg(), func() jwt.SigningMethod {
+		return SigningMethodSM2
+	})
+}
+
+func (x *signingMethodSM2) Alg() string {
+	return "SM2"
+}
+
+func (x *signingMethodSM2) Sign(signingString string, key interface{}) ([]byte, error) {
+	priKey, ok := key.(*sm2.PrivateKey)
+	if !ok {
+		return nil, jwt.ErrInvalidKeyType
+	}
+	return priKey.Sign(rand.Reader, []byte(signingString), sm2.DefaultSM2SignerOpts)
+}
+
+func (x *signingMethodSM2) Verify(signingString string, sig []byte, key interface{}) error {
+	pubKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+	if !sm2.VerifyASN1WithSM2(pubKey, nil, []byte(signingString), sig) {
+		return jwt.ErrSignatureInvalid
+	}
+	return nil
+}
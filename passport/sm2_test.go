@@ -0,0 +1,43 @@
+package passport_test
+
+import (
+	"crypto/rand"
+	"github.com/emmansun/gmsm/sm2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/passport"
+	"testing"
+	"time"
+)
+
+func TestSigningMethodSM2(t *testing.T) {
+	key, err := sm2.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	signer := passport.New(
+		passport.SetIssuer("dev"),
+		passport.SetSigningKey(passport.SigningMethodSM2, key),
+	)
+	verifier := passport.New(
+		passport.SetIssuer("dev"),
+		passport.SetVerifyKey(passport.SigningMethodSM2, key.Public()),
+	)
+	ts, err := signer.Create(passport.NewClaims(userId1, time.Hour*2).SetJTI(jti1))
+	assert.NoError(t, err)
+	claims, err := verifier.Verify(ts)
+	assert.NoError(t, err)
+	assert.Equal(t, userId1, claims.ActiveId)
+	assert.Equal(t, jti1, claims.ID)
+
+	otherKey, err := sm2.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	other := passport.New(
+		passport.SetVerifyKey(passport.SigningMethodSM2, otherKey.Public()),
+	)
+	_, err = other.Verify(ts)
+	assert.ErrorIs(t, err, jwt.ErrSignatureInvalid)
+
+	_, err = passport.SigningMethodSM2.Sign("text", key.Public())
+	assert.ErrorIs(t, err, jwt.ErrInvalidKeyType)
+	err = passport.SigningMethodSM2.Verify("text", []byte{}, key)
+	assert.ErrorIs(t, err, jwt.ErrInvalidKeyType)
+}
@@ -0,0 +1,73 @@
+package passport
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"reflect"
+)
+
+// Typed creates and verifies user defined claims embedding jwt.RegisteredClaims,
+// sharing the keys and options of the Passport.
+//
+//	type UserClaims struct {
+//		Role string `json:"role"`
+//		jwt.RegisteredClaims
+//	}
+//
+//	users := passport.NewTyped[UserClaims](x)
+type Typed[T any, P interface {
+	*T
+	jwt.Claims
+}] struct {
+	*Passport
+}
+
+func NewTyped[T any, P interface {
+	*T
+	jwt.Claims
+}](x *Passport) *Typed[T, P] {
+	return &Typed[T, P]{Passport: x}
+}
+
+func (x *Typed[T, P]) Create(claims P) (tokenString string, err error) {
+	if r := registered(claims); r != nil {
+		r.Issuer = x.Issuer
+	}
+	return x.sign(claims)
+}
+
+func (x *Typed[T, P]) Verify(tokenString string) (claims T, err error) {
+	if err = x.parse(tokenString, P(&claims)); err != nil {
+		return
+	}
+	return
+}
+
+// registered finds the embedded jwt.RegisteredClaims, nil when there is none.
+func registered(claims interface{}) *jwt.RegisteredClaims {
+	v := reflect.ValueOf(claims)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	f := v.FieldByName("RegisteredClaims")
+	if !f.IsValid() {
+		return nil
+	}
+	if f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return nil
+		}
+		r, _ := f.Interface().(*jwt.RegisteredClaims)
+		return r
+	}
+	if !f.CanAddr() {
+		return nil
+	}
+	r, _ := f.Addr().Interface().(*jwt.RegisteredClaims)
+	return r
+}
@@ -0,0 +1,76 @@
+package passport_test
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/passport"
+	"testing"
+	"time"
+)
+
+type UserClaims struct {
+	UserId  int64    `json:"uid"`
+	Roles   []string `json:"roles"`
+	Premium bool     `json:"premium"`
+
+	jwt.RegisteredClaims
+}
+
+func TestTyped(t *testing.T) {
+	users := passport.NewTyped[UserClaims](x1)
+	ts, err := users.Create(&UserClaims{
+		UserId:  9007199254740993,
+		Roles:   []string{"admin"},
+		Premium: true,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti1,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	assert.NoError(t, err)
+
+	claims, err := users.Verify(ts)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9007199254740993), claims.UserId)
+	assert.Equal(t, []string{"admin"}, claims.Roles)
+	assert.True(t, claims.Premium)
+	assert.Equal(t, jti1, claims.ID)
+	assert.Equal(t, x1.Issuer, claims.Issuer)
+
+	_, err = passport.NewTyped[UserClaims](x2).Verify(ts)
+	assert.Error(t, err)
+
+	// The default Claims reads the same token, minus the custom fields.
+	defaults, err := x1.Verify(ts)
+	assert.NoError(t, err)
+	assert.Equal(t, jti1, defaults.ID)
+
+	expired, err := users.Create(&UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+		},
+	})
+	assert.NoError(t, err)
+	_, err = users.Verify(expired)
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+}
+
+type PointerClaims struct {
+	Role string `json:"role"`
+
+	*jwt.RegisteredClaims
+}
+
+func TestTypedPointerRegisteredClaims(t *testing.T) {
+	x := passport.NewTyped[PointerClaims](x1)
+	ts, err := x.Create(&PointerClaims{
+		Role:             "guest",
+		RegisteredClaims: &jwt.RegisteredClaims{ID: jti2},
+	})
+	assert.NoError(t, err)
+	claims, err := x.Verify(ts)
+	assert.NoError(t, err)
+	assert.Equal(t, "guest", claims.Role)
+	assert.Equal(t, jti2, claims.ID)
+	assert.Equal(t, x1.Issuer, claims.Issuer)
+}
@@ -0,0 +1,151 @@
+package passport
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"time"
+)
+
+// SetAudience makes Verify accept only tokens for one of them.
+func SetAudience(v ...string) Option {
+	return func(x *Passport) {
+		x.Audience = v
+	}
+}
+
+// SetIssueAudience is stamped on tokens without audience by Create,
+// apart from SetAudience so that a service accepting several audiences mints tokens for one.
+func SetIssueAudience(v ...string) Option {
+	return func(x *Passport) {
+		x.IssueAudience = v
+	}
+}
+
+// SetStrictIssuer rejects tokens whose issuer differs from Passport.Issuer.
+func SetStrictIssuer(v bool) Option {
+	return func(x *Passport) {
+		x.StrictIssuer = v
+	}
+}
+
+// SetLeeway tolerates clock skew on exp, nbf and iat.
+func SetLeeway(v time.Duration) Option {
+	return func(x *Passport) {
+		x.Leeway = v
+	}
+}
+
+// SetMaxAge rejects tokens issued longer ago than v, whatever their exp.
+func SetMaxAge(v time.Duration) Option {
+	return func(x *Passport) {
+		x.MaxAge = v
+	}
+}
+
+// SetRequiredClaims takes registered claim names: iss, sub, aud, exp, nbf, iat, jti.
+func SetRequiredClaims(v ...string) Option {
+	return func(x *Passport) {
+		x.RequiredClaims = v
+	}
+}
+
+var (
+	ErrTokenExpired     = jwt.ErrTokenExpired
+	ErrTokenNotValidYet = jwt.ErrTokenNotValidYet
+	ErrInvalidAudience  = errors.New("the token audience is not accepted")
+	ErrInvalidIssuer    = errors.New("the token issuer does not match")
+	ErrTokenTooOld      = errors.New("the token exceeds the max age")
+	ErrMissingClaim     = errors.New("the token is missing a required claim")
+)
+
+func (x *Passport) parse(tokenString string, claims jwt.Claims) (err error) {
+	if x.Cipher != nil {
+		if tokenString, err = x.open(tokenString); err != nil {
+			return
+		}
+	}
+	if _, err = jwt.ParseWithClaims(tokenString, claims, x.keyFunc,
+		jwt.WithLeeway(x.Leeway),
+	); err != nil {
+		return
+	}
+	return x.validate(claims)
+}
+
+func (x *Passport) validate(claims jwt.Claims) (err error) {
+	for _, name := range x.RequiredClaims {
+		if !hasClaim(claims, name) {
+			return fmt.Errorf("%w: %s", ErrMissingClaim, name)
+		}
+	}
+	if x.StrictIssuer {
+		var iss string
+		if iss, err = claims.GetIssuer(); err != nil {
+			return
+		}
+		if iss != x.Issuer {
+			return ErrInvalidIssuer
+		}
+	}
+	if len(x.Audience) != 0 {
+		var aud jwt.ClaimStrings
+		if aud, err = claims.GetAudience(); err != nil {
+			return
+		}
+		if !matchAudience(x.Audience, aud) {
+			return ErrInvalidAudience
+		}
+	}
+	if x.MaxAge > 0 {
+		var iat *jwt.NumericDate
+		if iat, err = claims.GetIssuedAt(); err != nil {
+			return
+		}
+		if iat == nil {
+			return fmt.Errorf("%w: iat", ErrMissingClaim)
+		}
+		if time.Since(iat.Time) > x.MaxAge+x.Leeway {
+			return ErrTokenTooOld
+		}
+	}
+	return
+}
+
+func matchAudience(expected []string, aud jwt.ClaimStrings) bool {
+	for _, v := range aud {
+		for _, e := range expected {
+			if v == e {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasClaim(claims jwt.Claims, name string) bool {
+	switch name {
+	case "iss":
+		v, _ := claims.GetIssuer()
+		return v != ""
+	case "sub":
+		v, _ := claims.GetSubject()
+		return v != ""
+	case "aud":
+		v, _ := claims.GetAudience()
+		return len(v) != 0
+	case "exp":
+		v, _ := claims.GetExpirationTime()
+		return v != nil
+	case "nbf":
+		v, _ := claims.GetNotBefore()
+		return v != nil
+	case "iat":
+		v, _ := claims.GetIssuedAt()
+		return v != nil
+	case "jti":
+		r := registered(claims)
+		return r != nil && r.ID != ""
+	}
+	return false
+}
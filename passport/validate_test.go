@@ -0,0 +1,151 @@
+package passport_test
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/passport"
+	"testing"
+	"time"
+)
+
+func TestAudience(t *testing.T) {
+	admin := passport.New(
+		passport.SetIssuer("dev"),
+		passport.SetKey(key1),
+		passport.SetAudience("admin"),
+		passport.SetIssueAudience("admin"),
+	)
+	api := passport.New(
+		passport.SetIssuer("dev"),
+		passport.SetKey(key1),
+		passport.SetAudience("api", "mobile"),
+	)
+	ts, err := admin.Create(passport.NewClaims(userId1, time.Hour))
+	assert.NoError(t, err)
+	claims, err := admin.Verify(ts)
+	assert.NoError(t, err)
+	assert.Equal(t, jwt.ClaimStrings{"admin"}, claims.Audience)
+	_, err = api.Verify(ts)
+	assert.ErrorIs(t, err, passport.ErrInvalidAudience)
+
+	ts, err = admin.Create(passport.NewClaims(userId1, time.Hour).SetAudience("mobile"))
+	assert.NoError(t, err)
+	_, err = api.Verify(ts)
+	assert.NoError(t, err)
+
+	// Without audience the token is not accepted by an audience bound passport.
+	ts, err = x1.Create(passport.NewClaims(userId1, time.Hour))
+	assert.NoError(t, err)
+	_, err = api.Verify(ts)
+	assert.ErrorIs(t, err, passport.ErrInvalidAudience)
+
+	// Accepting both does not mint tokens for both.
+	console := passport.New(
+		passport.SetIssuer("dev"),
+		passport.SetKey(key1),
+		passport.SetAudience("admin", "api"),
+		passport.SetIssueAudience("api"),
+	)
+	ts, err = console.Create(passport.NewClaims(userId1, time.Hour))
+	assert.NoError(t, err)
+	claims, err = console.Verify(ts)
+	assert.NoError(t, err)
+	assert.Equal(t, jwt.ClaimStrings{"api"}, claims.Audience)
+	_, err = api.Verify(ts)
+	assert.NoError(t, err)
+	_, err = admin.Verify(ts)
+	assert.ErrorIs(t, err, passport.ErrInvalidAudience)
+}
+
+func TestStrictIssuer(t *testing.T) {
+	x := passport.New(
+		passport.SetIssuer("beta"),
+		passport.SetKey(key1),
+		passport.SetStrictIssuer(true),
+	)
+	ts, err := x1.Create(passport.NewClaims(userId1, time.Hour))
+	assert.NoError(t, err)
+	_, err = x.Verify(ts)
+	assert.ErrorIs(t, err, passport.ErrInvalidIssuer)
+	ts, err = x.Create(passport.NewClaims(userId1, time.Hour))
+	assert.NoError(t, err)
+	_, err = x.Verify(ts)
+	assert.NoError(t, err)
+}
+
+func TestLeeway(t *testing.T) {
+	ts, err := x1.Create(passport.NewClaims(userId1, -time.Second*5))
+	assert.NoError(t, err)
+	_, err = x1.Verify(ts)
+	assert.ErrorIs(t, err, passport.ErrTokenExpired)
+
+	x := passport.New(
+		passport.SetIssuer("dev"),
+		passport.SetKey(key1),
+		passport.SetLeeway(time.Minute),
+	)
+	_, err = x.Verify(ts)
+	assert.NoError(t, err)
+
+	claims := passport.NewClaims(userId1, time.Hour)
+	claims.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Second * 30))
+	ts, err = x1.Create(claims)
+	assert.NoError(t, err)
+	_, err = x1.Verify(ts)
+	assert.ErrorIs(t, err, passport.ErrTokenNotValidYet)
+	_, err = x.Verify(ts)
+	assert.NoError(t, err)
+}
+
+func TestMaxAge(t *testing.T) {
+	x := passport.New(
+		passport.SetIssuer("dev"),
+		passport.SetKey(key1),
+		passport.SetMaxAge(time.Hour),
+	)
+	claims := passport.NewClaims(userId1, time.Hour*24)
+	claims.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Hour * 2))
+	ts, err := x.Create(claims)
+	assert.NoError(t, err)
+	_, err = x.Verify(ts)
+	assert.ErrorIs(t, err, passport.ErrTokenTooOld)
+
+	claims = passport.NewClaims(userId1, time.Hour*24)
+	claims.IssuedAt = nil
+	ts, err = x.Create(claims)
+	assert.NoError(t, err)
+	_, err = x.Verify(ts)
+	assert.ErrorIs(t, err, passport.ErrMissingClaim)
+
+	ts, err = x.Create(passport.NewClaims(userId1, time.Hour*24))
+	assert.NoError(t, err)
+	_, err = x.Verify(ts)
+	assert.NoError(t, err)
+}
+
+func TestRequiredClaims(t *testing.T) {
+	x := passport.New(
+		passport.SetIssuer("dev"),
+		passport.SetKey(key1),
+		passport.SetRequiredClaims("exp", "jti", "sub"),
+	)
+	ts, err := x.Create(passport.NewClaims(userId1, time.Hour).SetJTI(jti1))
+	assert.NoError(t, err)
+	_, err = x.Verify(ts)
+	assert.ErrorIs(t, err, passport.ErrMissingClaim)
+	assert.ErrorContains(t, err, "sub")
+
+	claims := passport.NewClaims(userId1, time.Hour).SetJTI(jti1)
+	claims.Subject = userId1
+	ts, err = x.Create(claims)
+	assert.NoError(t, err)
+	_, err = x.Verify(ts)
+	assert.NoError(t, err)
+
+	users := passport.NewTyped[UserClaims](x)
+	ts, err = users.Create(&UserClaims{UserId: 1})
+	assert.NoError(t, err)
+	_, err = users.Verify(ts)
+	assert.ErrorIs(t, err, passport.ErrMissingClaim)
+	assert.ErrorContains(t, err, "exp")
+}
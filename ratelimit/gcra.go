@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// GCRA is the generic cell rate algorithm, Limit per Period spaced evenly, with up to Burst at once.
+// It stores only the theoretical arrival time.
+type GCRA struct {
+	RDb    redis.UniversalClient
+	Limit  int64
+	Period time.Duration
+	// Burst defaults to Limit.
+	Burst int64
+}
+
+func NewGCRA(rdb redis.UniversalClient, limit int64, period time.Duration) *GCRA {
+	return &GCRA{RDb: rdb, Limit: limit, Period: period, Burst: limit}
+}
+
+func (x *GCRA) Key(name string) string {
+	return fmt.Sprintf(`ratelimit:gcra:%s`, name)
+}
+
+var gcraScript = redis.NewScript(now + `
+local interval, burst, n = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local tolerance = interval * burst
+local tat = math.max(tonumber(redis.call('GET', KEYS[1])) or now, now)
+local new = tat + n * interval
+local at = new - tolerance
+if at > now then
+	local retry = -1
+	if n <= burst then
+		retry = math.ceil(at - now)
+	end
+	return {0, math.floor((now - (tat - tolerance)) / interval), retry, math.ceil(tat - now)}
+end
+redis.call('SET', KEYS[1], string.format('%.0f', new), 'PX', math.max(1, math.ceil((new - now) / 1000)))
+return {1, math.floor((now - at) / interval), 0, math.ceil(new - now)}
+`)
+
+func (x *GCRA) AllowN(ctx context.Context, name string, n int64) (*Result, error) {
+	if x.Limit <= 0 || x.Period.Microseconds() <= 0 {
+		return never(x.Limit), nil
+	}
+	return run(ctx, gcraScript, x.RDb, x.Key(name), x.Burst,
+		float64(x.Period.Microseconds())/float64(x.Limit), x.Burst, n,
+	)
+}
@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Local is an in-process GCRA, the fallback of a Policy while Redis is unavailable.
+// The limits count per instance, not across the servers.
+type Local struct {
+	Limit  int64
+	Period time.Duration
+	// Burst defaults to Limit.
+	Burst int64
+
+	mu      sync.Mutex
+	tats    map[string]time.Time
+	sweptAt time.Time
+}
+
+// NewLocal allows nothing when limit is 0.
+func NewLocal(limit int64, period time.Duration) *Local {
+	return &Local{
+		Limit:  limit,
+		Period: period,
+		Burst:  limit,
+		tats:   make(map[string]time.Time),
+	}
+}
+
+func (x *Local) AllowN(_ context.Context, name string, n int64) (*Result, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	now := time.Now()
+	if now.Sub(x.sweptAt) > x.Period {
+		for k, v := range x.tats {
+			if v.Before(now) {
+				delete(x.tats, k)
+			}
+		}
+		x.sweptAt = now
+	}
+
+	if x.Limit <= 0 || x.Period < time.Duration(x.Limit) {
+		return never(x.Limit), nil
+	}
+	interval := x.Period / time.Duration(x.Limit)
+	tolerance := interval * time.Duration(x.Burst)
+	tat := x.tats[name]
+	if tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(interval * time.Duration(n))
+	at := next.Add(-tolerance)
+	if at.After(now) {
+		r := &Result{
+			Limit:      x.Burst,
+			Remaining:  max(int64(now.Sub(tat.Add(-tolerance))/interval), 0),
+			RetryAfter: at.Sub(now),
+			ResetAfter: tat.Sub(now),
+		}
+		if n > x.Burst {
+			r.RetryAfter = -1
+		}
+		return r, nil
+	}
+	x.tats[name] = next
+	return &Result{
+		Allowed:    true,
+		Limit:      x.Burst,
+		Remaining:  int64(now.Sub(at) / interval),
+		ResetAfter: next.Sub(now),
+	}, nil
+}
@@ -0,0 +1,175 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/weplanx/go/help"
+	"github.com/weplanx/go/passport"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// KeyFunc extracts the key limited from the request, empty skips the policy.
+type KeyFunc func(ctx context.Context, c *app.RequestContext) string
+
+func ByIP() KeyFunc {
+	return func(ctx context.Context, c *app.RequestContext) string {
+		return c.ClientIP()
+	}
+}
+
+// ByActiveId limits the user authenticated by passport, requests without claims are skipped.
+func ByActiveId() KeyFunc {
+	return func(ctx context.Context, c *app.RequestContext) string {
+		claims, ok := passport.GetClaims(c)
+		if !ok {
+			return ""
+		}
+		return claims.ActiveId
+	}
+}
+
+// ByHeader limits by e.g. an API key.
+func ByHeader(name string) KeyFunc {
+	return func(ctx context.Context, c *app.RequestContext) string {
+		return string(c.GetHeader(name))
+	}
+}
+
+// ByRoute shares one quota among all the clients of the route.
+func ByRoute() KeyFunc {
+	return func(ctx context.Context, c *app.RequestContext) string {
+		return c.FullPath()
+	}
+}
+
+// Policy limits the requests of Routes by Key, Name namespaces the keys.
+type Policy struct {
+	Name    string
+	Limiter Limiter
+	Key     KeyFunc
+	// Routes are patterns as registered, optionally prefixed with the method, e.g. "POST /login",
+	// all routes when empty.
+	Routes []string
+	// Fallback is used while Limiter fails, e.g. Local, otherwise Throttle.FailOpen decides.
+	Fallback Limiter
+}
+
+type PolicyOption func(x *Policy)
+
+func NewPolicy(name string, limiter Limiter, key KeyFunc, options ...PolicyOption) *Policy {
+	x := &Policy{Name: name, Limiter: limiter, Key: key}
+	for _, v := range options {
+		v(x)
+	}
+	return x
+}
+
+func SetRoutes(v ...string) PolicyOption {
+	return func(x *Policy) {
+		x.Routes = v
+	}
+}
+
+// SetFallback limits locally while Limiter fails.
+func SetFallback(v Limiter) PolicyOption {
+	return func(x *Policy) {
+		x.Fallback = v
+	}
+}
+
+func (x *Policy) match(c *app.RequestContext) bool {
+	if len(x.Routes) == 0 {
+		return true
+	}
+	path := c.FullPath()
+	for _, v := range x.Routes {
+		if v == path || v == string(c.Method())+" "+path {
+			return true
+		}
+	}
+	return false
+}
+
+type Throttle struct {
+	Policies []*Policy
+	// FailOpen lets requests pass when a policy without Fallback fails, otherwise they get 503.
+	FailOpen bool
+}
+
+type Option func(x *Throttle)
+
+func New(options ...Option) *Throttle {
+	x := new(Throttle)
+	for _, v := range options {
+		v(x)
+	}
+	return x
+}
+
+func SetPolicies(v ...*Policy) Option {
+	return func(x *Throttle) {
+		x.Policies = v
+	}
+}
+
+func SetFailOpen(v bool) Option {
+	return func(x *Throttle) {
+		x.FailOpen = v
+	}
+}
+
+// Limit applies every matching policy in order, sets the RateLimit headers
+// of the most restrictive one, and responds 429 in the help.R shape when one is exceeded.
+func (x *Throttle) Limit() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		var tightest *Result
+		for _, p := range x.Policies {
+			if !p.match(c) {
+				continue
+			}
+			key := p.Key(ctx, c)
+			if key == "" {
+				continue
+			}
+			r, err := p.Limiter.AllowN(ctx, p.Name+":"+key, 1)
+			if err != nil && p.Fallback != nil {
+				r, err = p.Fallback.AllowN(ctx, p.Name+":"+key, 1)
+			}
+			if err != nil {
+				if x.FailOpen {
+					continue
+				}
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, help.Fail(0, "the rate limiter is unavailable"))
+				return
+			}
+			if !r.Allowed {
+				headers(c, r)
+				if r.RetryAfter > 0 {
+					c.Header("Retry-After", seconds(r.RetryAfter))
+				}
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, help.Fail(0, "too many requests"))
+				return
+			}
+			if tightest == nil || r.Remaining < tightest.Remaining {
+				tightest = r
+			}
+		}
+		if tightest != nil {
+			headers(c, tightest)
+		}
+		c.Next(ctx)
+	}
+}
+
+func headers(c *app.RequestContext, r *Result) {
+	c.Header("RateLimit-Limit", strconv.FormatInt(r.Limit, 10))
+	c.Header("RateLimit-Remaining", strconv.FormatInt(r.Remaining, 10))
+	c.Header("RateLimit-Reset", seconds(r.ResetAfter))
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,133 @@
+package ratelimit_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/help"
+	"github.com/weplanx/go/passport"
+	"github.com/weplanx/go/ratelimit"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newLimitEngine(x *ratelimit.Throttle) *route.Engine {
+	engine := route.NewEngine(config.NewOptions([]config.Option{}))
+	engine.Use(func(ctx context.Context, c *app.RequestContext) {
+		if v := c.Query("user"); v != "" {
+			c.Set(passport.ClaimsKey, passport.Claims{ActiveId: v})
+		}
+		c.Next(ctx)
+	}, x.Limit())
+	handler := func(ctx context.Context, c *app.RequestContext) {
+		c.JSON(http.StatusOK, help.Ok())
+	}
+	engine.GET("/users/:id", handler)
+	engine.POST("/login", handler)
+	engine.GET("/login", handler)
+	return engine
+}
+
+func TestLimit(t *testing.T) {
+	ns := help.Uuid()
+	x := ratelimit.New(ratelimit.SetPolicies(
+		ratelimit.NewPolicy(ns+":ip", ratelimit.NewSlidingWindow(rdb, 3, time.Second*10), ratelimit.ByIP()),
+		ratelimit.NewPolicy(ns+":login", ratelimit.NewGCRA(rdb, 1, time.Second*10), ratelimit.ByHeader("X-Api-Key"), ratelimit.SetRoutes("POST /login")),
+	))
+	engine := newLimitEngine(x)
+
+	resp := ut.PerformRequest(engine, "GET", "/users/1", nil).Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, "3", string(resp.Header.Peek("RateLimit-Limit")))
+	assert.Equal(t, "2", string(resp.Header.Peek("RateLimit-Remaining")))
+	reset, err := strconv.Atoi(string(resp.Header.Peek("RateLimit-Reset")))
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, reset, 10)
+	assert.LessOrEqual(t, reset, 20)
+
+	// the login policy is the tighter one
+	resp = ut.PerformRequest(engine, "POST", "/login", nil, ut.Header{Key: "X-Api-Key", Value: "k1"}).Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, "1", string(resp.Header.Peek("RateLimit-Limit")))
+	assert.Equal(t, "0", string(resp.Header.Peek("RateLimit-Remaining")))
+
+	resp = ut.PerformRequest(engine, "POST", "/login", nil, ut.Header{Key: "X-Api-Key", Value: "k1"}).Result()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode())
+	assert.Equal(t, "10", string(resp.Header.Peek("Retry-After")))
+	var r help.R
+	assert.NoError(t, json.Unmarshal(resp.Body(), &r))
+	assert.Equal(t, "too many requests", r.Message)
+
+	// other methods of the route are not matched by the login policy
+	resp = ut.PerformRequest(engine, "GET", "/login", nil, ut.Header{Key: "X-Api-Key", Value: "k1"}).Result()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode())
+	assert.Equal(t, "3", string(resp.Header.Peek("RateLimit-Limit")))
+}
+
+func TestLimitByActiveId(t *testing.T) {
+	ns := help.Uuid()
+	x := ratelimit.New(ratelimit.SetPolicies(
+		ratelimit.NewPolicy(ns, ratelimit.NewTokenBucket(rdb, 1, time.Second*10), ratelimit.ByActiveId()),
+	))
+	engine := newLimitEngine(x)
+	for i := 0; i < 3; i++ {
+		resp := ut.PerformRequest(engine, "GET", "/users/1", nil).Result()
+		assert.Equal(t, http.StatusOK, resp.StatusCode())
+	}
+	resp := ut.PerformRequest(engine, "GET", "/users/1?user=u1", nil).Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	resp = ut.PerformRequest(engine, "GET", "/users/1?user=u1", nil).Result()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode())
+	resp = ut.PerformRequest(engine, "GET", "/users/1?user=u2", nil).Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+}
+
+func TestLimitByRoute(t *testing.T) {
+	ns := help.Uuid()
+	x := ratelimit.New(ratelimit.SetPolicies(
+		ratelimit.NewPolicy(ns, ratelimit.NewSlidingLog(rdb, 1, time.Second*10), ratelimit.ByRoute()),
+	))
+	engine := newLimitEngine(x)
+	resp := ut.PerformRequest(engine, "GET", "/users/1", nil).Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	resp = ut.PerformRequest(engine, "GET", "/users/2", nil).Result()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode())
+	resp = ut.PerformRequest(engine, "GET", "/login", nil).Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+}
+
+func TestLimitUnavailable(t *testing.T) {
+	down := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: time.Millisecond * 100})
+	limiter := ratelimit.NewGCRA(down, 1, time.Second*10)
+
+	engine := newLimitEngine(ratelimit.New(ratelimit.SetPolicies(
+		ratelimit.NewPolicy("down", limiter, ratelimit.ByIP()),
+	)))
+	resp := ut.PerformRequest(engine, "GET", "/users/1", nil).Result()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+
+	engine = newLimitEngine(ratelimit.New(
+		ratelimit.SetPolicies(ratelimit.NewPolicy("down", limiter, ratelimit.ByIP())),
+		ratelimit.SetFailOpen(true),
+	))
+	for i := 0; i < 3; i++ {
+		resp = ut.PerformRequest(engine, "GET", "/users/1", nil).Result()
+		assert.Equal(t, http.StatusOK, resp.StatusCode())
+	}
+
+	engine = newLimitEngine(ratelimit.New(ratelimit.SetPolicies(
+		ratelimit.NewPolicy("down", limiter, ratelimit.ByIP(),
+			ratelimit.SetFallback(ratelimit.NewLocal(1, time.Second*10))),
+	)))
+	resp = ut.PerformRequest(engine, "GET", "/users/1", nil).Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	resp = ut.PerformRequest(engine, "GET", "/users/1", nil).Result()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode())
+}
@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// Limiter is implemented by SlidingLog, SlidingWindow, TokenBucket and GCRA,
+// all atomic by Lua scripts and timed by the Redis clock.
+type Limiter interface {
+	// AllowN takes n from the quota of name, nothing is taken when it is not allowed.
+	AllowN(ctx context.Context, name string, n int64) (*Result, error)
+}
+
+// Allow takes 1 from the quota of name.
+func Allow(ctx context.Context, l Limiter, name string) (*Result, error) {
+	return l.AllowN(ctx, name, 1)
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int64
+	Remaining int64
+	// RetryAfter is the wait until the request would be allowed, 0 when allowed,
+	// and -1 when it never will be, n is larger than the limit.
+	RetryAfter time.Duration
+	// ResetAfter is the wait until the full quota is back.
+	ResetAfter time.Duration
+}
+
+// never is the Result of the limiters allowing nothing, e.g. a Limit or Period of 0.
+func never(limit int64) *Result {
+	return &Result{Limit: max(limit, 0), RetryAfter: -1}
+}
+
+// now is the Redis clock in microseconds, shared by the application servers.
+const now = `
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+`
+
+// run parses the reply {allowed, remaining, retry, reset} of the scripts, times in microseconds.
+func run(ctx context.Context, script *redis.Script, rdb redis.UniversalClient, key string, limit int64, args ...interface{}) (r *Result, err error) {
+	var v []int64
+	if v, err = script.Run(ctx, rdb, []string{key}, args...).Int64Slice(); err != nil {
+		return
+	}
+	r = &Result{
+		Allowed:    v[0] == 1,
+		Limit:      limit,
+		Remaining:  max(v[1], 0),
+		RetryAfter: time.Duration(v[2]) * time.Microsecond,
+		ResetAfter: time.Duration(max(v[3], 0)) * time.Microsecond,
+	}
+	if v[2] < 0 {
+		r.RetryAfter = -1
+	}
+	return
+}
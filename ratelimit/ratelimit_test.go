@@ -0,0 +1,167 @@
+package ratelimit_test
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/help"
+	"github.com/weplanx/go/ratelimit"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var rdb *redis.Client
+
+func TestMain(m *testing.M) {
+	opts, err := redis.ParseURL(os.Getenv("DATABASE_REDIS"))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	rdb = redis.NewClient(opts)
+	os.Exit(m.Run())
+}
+
+func limiters(limit int64, period time.Duration) map[string]ratelimit.Limiter {
+	return map[string]ratelimit.Limiter{
+		"sliding_log":    ratelimit.NewSlidingLog(rdb, limit, period),
+		"sliding_window": ratelimit.NewSlidingWindow(rdb, limit, period),
+		"token_bucket":   ratelimit.NewTokenBucket(rdb, limit, period),
+		"gcra":           ratelimit.NewGCRA(rdb, limit, period),
+		"local":          ratelimit.NewLocal(limit, period),
+	}
+}
+
+func TestAllow(t *testing.T) {
+	ctx := context.TODO()
+	for name, l := range limiters(5, time.Second) {
+		t.Run(name, func(t *testing.T) {
+			key := help.Uuid()
+			for i := int64(1); i <= 5; i++ {
+				r, err := ratelimit.Allow(ctx, l, key)
+				assert.NoError(t, err)
+				assert.True(t, r.Allowed)
+				assert.Equal(t, int64(5), r.Limit)
+				assert.Equal(t, 5-i, r.Remaining)
+				assert.Equal(t, time.Duration(0), r.RetryAfter)
+				assert.Greater(t, r.ResetAfter, time.Duration(0))
+				assert.LessOrEqual(t, r.ResetAfter, time.Second*2)
+			}
+			r, err := ratelimit.Allow(ctx, l, key)
+			assert.NoError(t, err)
+			assert.False(t, r.Allowed)
+			assert.Equal(t, int64(0), r.Remaining)
+			assert.Greater(t, r.RetryAfter, time.Duration(0))
+			assert.LessOrEqual(t, r.RetryAfter, time.Second*2)
+
+			time.Sleep(r.RetryAfter + time.Millisecond*30)
+			r, err = ratelimit.Allow(ctx, l, key)
+			assert.NoError(t, err)
+			assert.True(t, r.Allowed)
+		})
+	}
+}
+
+func TestAllowN(t *testing.T) {
+	ctx := context.TODO()
+	for name, l := range limiters(5, time.Second*10) {
+		t.Run(name, func(t *testing.T) {
+			key := help.Uuid()
+			r, err := l.AllowN(ctx, key, 3)
+			assert.NoError(t, err)
+			assert.True(t, r.Allowed)
+			assert.Equal(t, int64(2), r.Remaining)
+
+			r, err = l.AllowN(ctx, key, 3)
+			assert.NoError(t, err)
+			assert.False(t, r.Allowed)
+			assert.Equal(t, int64(2), r.Remaining)
+			assert.Greater(t, r.RetryAfter, time.Duration(0))
+
+			r, err = l.AllowN(ctx, key, 2)
+			assert.NoError(t, err)
+			assert.True(t, r.Allowed)
+
+			r, err = l.AllowN(ctx, key, 6)
+			assert.NoError(t, err)
+			assert.False(t, r.Allowed)
+			assert.Equal(t, time.Duration(-1), r.RetryAfter)
+		})
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	ctx := context.TODO()
+	for name, l := range limiters(10, time.Second*10) {
+		t.Run(name, func(t *testing.T) {
+			key := help.Uuid()
+			var wg sync.WaitGroup
+			var allowed atomic.Int64
+			for i := 0; i < 30; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					r, err := ratelimit.Allow(ctx, l, key)
+					assert.NoError(t, err)
+					if r.Allowed {
+						allowed.Add(1)
+					}
+				}()
+			}
+			wg.Wait()
+			assert.Equal(t, int64(10), allowed.Load())
+		})
+	}
+}
+
+func TestGCRABurst(t *testing.T) {
+	ctx := context.TODO()
+	l := ratelimit.NewGCRA(rdb, 10, time.Second)
+	l.Burst = 2
+	key := help.Uuid()
+	for i := 0; i < 2; i++ {
+		r, err := ratelimit.Allow(ctx, l, key)
+		assert.NoError(t, err)
+		assert.True(t, r.Allowed)
+	}
+	r, err := ratelimit.Allow(ctx, l, key)
+	assert.NoError(t, err)
+	assert.False(t, r.Allowed)
+	assert.Equal(t, int64(2), r.Limit)
+	// one emission interval
+	assert.LessOrEqual(t, r.RetryAfter, time.Millisecond*100)
+}
+
+func TestSlidingWindowWeight(t *testing.T) {
+	ctx := context.TODO()
+	l := ratelimit.NewSlidingWindow(rdb, 4, time.Millisecond*500)
+	key := help.Uuid()
+	_, err := l.AllowN(ctx, key, 4)
+	assert.NoError(t, err)
+	r, err := ratelimit.Allow(ctx, l, key)
+	assert.NoError(t, err)
+	assert.False(t, r.Allowed)
+	// the previous window still weighs in after it ends
+	assert.Greater(t, r.ResetAfter, time.Millisecond*500)
+	time.Sleep(r.RetryAfter + time.Millisecond*30)
+	r, err = ratelimit.Allow(ctx, l, key)
+	assert.NoError(t, err)
+	assert.True(t, r.Allowed)
+}
+
+func TestZero(t *testing.T) {
+	ctx := context.TODO()
+	for _, ls := range []map[string]ratelimit.Limiter{limiters(0, time.Second), limiters(5, 0)} {
+		for name, l := range ls {
+			t.Run(name, func(t *testing.T) {
+				r, err := ratelimit.Allow(ctx, l, help.Uuid())
+				assert.NoError(t, err)
+				assert.False(t, r.Allowed)
+				assert.Equal(t, time.Duration(-1), r.RetryAfter)
+			})
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/weplanx/go/help"
+	"time"
+)
+
+// SlidingLog records every request in a sorted set, exact but the memory grows with Limit.
+type SlidingLog struct {
+	RDb    redis.UniversalClient
+	Limit  int64
+	Window time.Duration
+}
+
+func NewSlidingLog(rdb redis.UniversalClient, limit int64, window time.Duration) *SlidingLog {
+	return &SlidingLog{RDb: rdb, Limit: limit, Window: window}
+}
+
+func (x *SlidingLog) Key(name string) string {
+	return fmt.Sprintf(`ratelimit:log:%s`, name)
+}
+
+var slidingLogScript = redis.NewScript(now + `
+local limit, window, n = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', string.format('%.0f', now - window))
+local count = redis.call('ZCARD', KEYS[1])
+local reset = 0
+local last = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+if #last > 0 then
+	reset = tonumber(last[2]) + window - now
+end
+if count + n > limit then
+	if n > limit then
+		return {0, limit - count, -1, reset}
+	end
+	local k = count + n - limit - 1
+	local oldest = redis.call('ZRANGE', KEYS[1], k, k, 'WITHSCORES')
+	return {0, limit - count, tonumber(oldest[2]) + window - now, reset}
+end
+for i = 1, n do
+	redis.call('ZADD', KEYS[1], string.format('%.0f', now), ARGV[4] .. ':' .. i)
+end
+redis.call('PEXPIRE', KEYS[1], math.ceil(window / 1000))
+return {1, limit - count - n, 0, window}
+`)
+
+func (x *SlidingLog) AllowN(ctx context.Context, name string, n int64) (*Result, error) {
+	if x.Limit <= 0 || x.Window.Microseconds() <= 0 {
+		return never(x.Limit), nil
+	}
+	return run(ctx, slidingLogScript, x.RDb, x.Key(name), x.Limit,
+		x.Limit, x.Window.Microseconds(), n, help.Uuid(),
+	)
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// SlidingWindow weights the count of the previous fixed window by its overlap,
+// an approximation of SlidingLog in constant memory.
+type SlidingWindow struct {
+	RDb    redis.UniversalClient
+	Limit  int64
+	Window time.Duration
+}
+
+func NewSlidingWindow(rdb redis.UniversalClient, limit int64, window time.Duration) *SlidingWindow {
+	return &SlidingWindow{RDb: rdb, Limit: limit, Window: window}
+}
+
+func (x *SlidingWindow) Key(name string) string {
+	return fmt.Sprintf(`ratelimit:window:%s`, name)
+}
+
+var slidingWindowScript = redis.NewScript(now + `
+local limit, window, n = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local v = redis.call('HMGET', KEYS[1], 'start', 'cur', 'prev')
+local start = now - now % window
+local cur, prev = tonumber(v[2]) or 0, tonumber(v[3]) or 0
+if tonumber(v[1]) ~= start then
+	if tonumber(v[1]) == start - window then
+		prev = cur
+	else
+		prev = 0
+	end
+	cur = 0
+end
+local elapsed = now - start
+local count = prev * (window - elapsed) / window + cur
+if count + n > limit then
+	local retry = -1
+	if n <= limit then
+		if prev > 0 and cur + n <= limit then
+			-- the previous window decays enough within this one
+			retry = start + window * (1 - (limit - cur - n) / prev) - now
+		else
+			-- this window becomes the previous one and decays
+			retry = start + window + math.max(0, window * (1 - (limit - n) / cur)) - now
+		end
+	end
+	return {0, math.floor(limit - count), math.ceil(math.max(retry, -1)), start + 2 * window - now}
+end
+cur = cur + n
+redis.call('HSET', KEYS[1], 'start', string.format('%.0f', start), 'cur', cur, 'prev', prev)
+redis.call('PEXPIRE', KEYS[1], math.ceil(2 * window / 1000))
+return {1, math.floor(limit - count - n), 0, start + 2 * window - now}
+`)
+
+func (x *SlidingWindow) AllowN(ctx context.Context, name string, n int64) (*Result, error) {
+	if x.Limit <= 0 || x.Window.Microseconds() <= 0 {
+		return never(x.Limit), nil
+	}
+	return run(ctx, slidingWindowScript, x.RDb, x.Key(name), x.Limit,
+		x.Limit, x.Window.Microseconds(), n,
+	)
+}
@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// TokenBucket holds up to Limit tokens refilled evenly over Period, bursts drain the bucket.
+type TokenBucket struct {
+	RDb    redis.UniversalClient
+	Limit  int64
+	Period time.Duration
+}
+
+func NewTokenBucket(rdb redis.UniversalClient, limit int64, period time.Duration) *TokenBucket {
+	return &TokenBucket{RDb: rdb, Limit: limit, Period: period}
+}
+
+func (x *TokenBucket) Key(name string) string {
+	return fmt.Sprintf(`ratelimit:bucket:%s`, name)
+}
+
+var tokenBucketScript = redis.NewScript(now + `
+local limit, period, n = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local rate = limit / period
+local v = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens, ts = tonumber(v[1]) or limit, tonumber(v[2]) or now
+tokens = math.min(limit, tokens + math.max(0, now - ts) * rate)
+if tokens < n then
+	local retry = -1
+	if n <= limit then
+		retry = math.ceil((n - tokens) / rate)
+	end
+	return {0, math.floor(tokens), retry, math.ceil((limit - tokens) / rate)}
+end
+tokens = tokens - n
+local reset = math.ceil((limit - tokens) / rate)
+redis.call('HSET', KEYS[1], 'tokens', tokens, 'ts', string.format('%.0f', now))
+redis.call('PEXPIRE', KEYS[1], math.max(1, math.ceil(reset / 1000)))
+return {1, math.floor(tokens), 0, reset}
+`)
+
+func (x *TokenBucket) AllowN(ctx context.Context, name string, n int64) (*Result, error) {
+	if x.Limit <= 0 || x.Period.Microseconds() <= 0 {
+		return never(x.Limit), nil
+	}
+	return run(ctx, tokenBucketScript, x.RDb, x.Key(name), x.Limit,
+		x.Limit, x.Period.Microseconds(), n,
+	)
+}
@@ -0,0 +1,233 @@
+package store
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Memory keeps the keys in process, for tests and single binary deployments.
+// Expired keys are dropped on access and swept at most once per minute on writes.
+type Memory struct {
+	mu      sync.Mutex
+	items   map[string]item
+	sweptAt time.Time
+}
+
+type item struct {
+	value     string
+	expiresAt time.Time
+}
+
+func (x item) expired(now time.Time) bool {
+	return !x.expiresAt.IsZero() && !now.Before(x.expiresAt)
+}
+
+func (x item) ttl(now time.Time) time.Duration {
+	if x.expiresAt.IsZero() {
+		return -1
+	}
+	return x.expiresAt.Sub(now)
+}
+
+func NewMemory() *Memory {
+	return &Memory{items: make(map[string]item)}
+}
+
+func expiresAt(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
+
+// load must be called with the lock held.
+func (x *Memory) load(key string, now time.Time) (v item, ok bool) {
+	if v, ok = x.items[key]; ok && v.expired(now) {
+		delete(x.items, key)
+		return item{}, false
+	}
+	return
+}
+
+// store must be called with the lock held.
+func (x *Memory) store(key string, v item, now time.Time) {
+	if now.Sub(x.sweptAt) > time.Minute {
+		for k, v := range x.items {
+			if v.expired(now) {
+				delete(x.items, k)
+			}
+		}
+		x.sweptAt = now
+	}
+	x.items[key] = v
+}
+
+func (x *Memory) Set(_ context.Context, key string, value string, ttl time.Duration) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	now := time.Now()
+	x.store(key, item{value: value, expiresAt: expiresAt(now, ttl)}, now)
+	return nil
+}
+
+func (x *Memory) Get(_ context.Context, key string) (string, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	v, ok := x.load(key, time.Now())
+	if !ok {
+		return "", ErrNotExists
+	}
+	return v.value, nil
+}
+
+func (x *Memory) Exists(_ context.Context, key string) (bool, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	_, ok := x.load(key, time.Now())
+	return ok, nil
+}
+
+func (x *Memory) Del(_ context.Context, keys ...string) (n int64, _ error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	now := time.Now()
+	for _, key := range keys {
+		if _, ok := x.load(key, now); ok {
+			delete(x.items, key)
+			n++
+		}
+	}
+	return
+}
+
+func (x *Memory) Incr(ctx context.Context, key string, ttl time.Duration) (n int64, err error) {
+	n, _, err = x.IncrTTL(ctx, key, ttl)
+	return
+}
+
+func (x *Memory) IncrTTL(_ context.Context, key string, ttl time.Duration) (n int64, _ time.Duration, err error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	now := time.Now()
+	if n, err = x.incr(key, ttl, now); err != nil {
+		return
+	}
+	return n, x.items[key].ttl(now), nil
+}
+
+// incr must be called with the lock held.
+func (x *Memory) incr(key string, ttl time.Duration, now time.Time) (n int64, err error) {
+	v, ok := x.load(key, now)
+	if ok {
+		if n, err = strconv.ParseInt(v.value, 10, 64); err != nil {
+			return
+		}
+	}
+	n++
+	v.value = strconv.FormatInt(n, 10)
+	if v.expiresAt.IsZero() {
+		v.expiresAt = expiresAt(now, ttl)
+	}
+	x.store(key, v, now)
+	return
+}
+
+func (x *Memory) Expire(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	now := time.Now()
+	v, ok := x.load(key, now)
+	if !ok {
+		return false, nil
+	}
+	v.expiresAt = expiresAt(now, ttl)
+	x.store(key, v, now)
+	return true, nil
+}
+
+func (x *Memory) TTL(_ context.Context, key string) (time.Duration, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	now := time.Now()
+	v, ok := x.load(key, now)
+	if !ok {
+		return 0, ErrNotExists
+	}
+	return v.ttl(now), nil
+}
+
+func (x *Memory) Attempt(_ context.Context, key string, counter string, max int64) (_ string, n int64, err error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	now := time.Now()
+	v, ok := x.load(key, now)
+	if !ok {
+		return "", 0, ErrNotExists
+	}
+	c, ok := x.load(counter, now)
+	if ok {
+		if n, err = strconv.ParseInt(c.value, 10, 64); err != nil {
+			return
+		}
+	} else {
+		c.expiresAt = v.expiresAt
+	}
+	n++
+	if n >= max {
+		delete(x.items, key)
+		delete(x.items, counter)
+		return v.value, n, nil
+	}
+	c.value = strconv.FormatInt(n, 10)
+	x.store(counter, c, now)
+	return v.value, n, nil
+}
+
+func (x *Memory) Escalate(_ context.Context, counter string, locked string, lockouts string,
+	max int64, window time.Duration, durations []time.Duration, decay time.Duration) (_ time.Duration, err error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	now := time.Now()
+	if v, ok := x.load(locked, now); ok {
+		return v.ttl(now), nil
+	}
+	var n int64
+	if n, err = x.incr(counter, window, now); err != nil || n < max {
+		return
+	}
+	var k int64
+	if k, err = x.incr(lockouts, 0, now); err != nil {
+		return
+	}
+	d := durations[min(int(k), len(durations))-1]
+	x.store(lockouts, item{value: strconv.FormatInt(k, 10), expiresAt: expiresAt(now, d+decay)}, now)
+	x.store(locked, item{value: strconv.FormatInt(k, 10), expiresAt: expiresAt(now, d)}, now)
+	delete(x.items, counter)
+	return d, nil
+}
+
+func (x *Memory) CompareAndDelete(_ context.Context, key string, value string) (bool, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	v, ok := x.load(key, time.Now())
+	if !ok || v.value != value {
+		return false, nil
+	}
+	delete(x.items, key)
+	return true, nil
+}
+
+func (x *Memory) Peek(_ context.Context, keys ...string) ([]Entry, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	now := time.Now()
+	entries := make([]Entry, len(keys))
+	for i, key := range keys {
+		if v, ok := x.load(key, now); ok {
+			entries[i] = Entry{Exists: true, Value: v.value, TTL: v.ttl(now)}
+		}
+	}
+	return entries, nil
+}
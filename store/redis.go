@@ -0,0 +1,206 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// Redis works with a single node, Sentinel and Cluster by redis.UniversalClient.
+type Redis struct {
+	RDb redis.UniversalClient
+}
+
+func NewRedis(rdb redis.UniversalClient) *Redis {
+	return &Redis{RDb: rdb}
+}
+
+func (x *Redis) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	return x.RDb.Set(ctx, key, value, ttl).Err()
+}
+
+func (x *Redis) Get(ctx context.Context, key string) (value string, err error) {
+	if value, err = x.RDb.Get(ctx, key).Result(); errors.Is(err, redis.Nil) {
+		err = ErrNotExists
+	}
+	return
+}
+
+func (x *Redis) Exists(ctx context.Context, key string) (_ bool, err error) {
+	var n int64
+	if n, err = x.RDb.Exists(ctx, key).Result(); err != nil {
+		return
+	}
+	return n != 0, nil
+}
+
+// Del deletes the keys one by one in a pipeline, they may live in different cluster slots.
+func (x *Redis) Del(ctx context.Context, keys ...string) (n int64, err error) {
+	var cmds []redis.Cmder
+	if cmds, err = x.RDb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, key := range keys {
+			p.Del(ctx, key)
+		}
+		return nil
+	}); err != nil {
+		return
+	}
+	for _, cmd := range cmds {
+		n += cmd.(*redis.IntCmd).Val()
+	}
+	return
+}
+
+var incrScript = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl == -1 and tonumber(ARGV[1]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {n, ttl}
+`)
+
+func (x *Redis) Incr(ctx context.Context, key string, ttl time.Duration) (n int64, err error) {
+	n, _, err = x.IncrTTL(ctx, key, ttl)
+	return
+}
+
+func (x *Redis) IncrTTL(ctx context.Context, key string, ttl time.Duration) (_ int64, _ time.Duration, err error) {
+	var v []int64
+	if v, err = incrScript.Run(ctx, x.RDb, []string{key}, ttl.Milliseconds()).Int64Slice(); err != nil {
+		return
+	}
+	return v[0], milliseconds(v[1]), nil
+}
+
+// milliseconds keeps -1 of PTTL.
+func milliseconds(v int64) time.Duration {
+	if v < 0 {
+		return time.Duration(v)
+	}
+	return time.Duration(v) * time.Millisecond
+}
+
+func (x *Redis) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if ttl <= 0 {
+		return x.RDb.Persist(ctx, key).Result()
+	}
+	return x.RDb.PExpire(ctx, key, ttl).Result()
+}
+
+func (x *Redis) TTL(ctx context.Context, key string) (ttl time.Duration, err error) {
+	if ttl, err = x.RDb.PTTL(ctx, key).Result(); err != nil {
+		return
+	}
+	if ttl == -2 {
+		return 0, ErrNotExists
+	}
+	return
+}
+
+var attemptScript = redis.NewScript(`
+local v = redis.call('GET', KEYS[1])
+if not v then
+	return false
+end
+local n = redis.call('INCR', KEYS[2])
+if n == 1 then
+	local ttl = redis.call('PTTL', KEYS[1])
+	if ttl > 0 then
+		redis.call('PEXPIRE', KEYS[2], ttl)
+	end
+end
+if n >= tonumber(ARGV[1]) then
+	redis.call('DEL', KEYS[1], KEYS[2])
+end
+return {v, n}
+`)
+
+func (x *Redis) Attempt(ctx context.Context, key string, counter string, max int64) (_ string, _ int64, err error) {
+	var v []interface{}
+	if v, err = attemptScript.Run(ctx, x.RDb, []string{key, counter}, max).Slice(); err != nil {
+		if errors.Is(err, redis.Nil) {
+			err = ErrNotExists
+		}
+		return
+	}
+	return v[0].(string), v[1].(int64), nil
+}
+
+var escalateScript = redis.NewScript(`
+local d = redis.call('PTTL', KEYS[2])
+if d ~= -2 then
+	return d
+end
+local n = redis.call('INCR', KEYS[1])
+if redis.call('PTTL', KEYS[1]) == -1 and tonumber(ARGV[2]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+if n < tonumber(ARGV[1]) then
+	return 0
+end
+local k = redis.call('INCR', KEYS[3])
+d = tonumber(ARGV[3 + math.min(k, #ARGV - 3)])
+redis.call('SET', KEYS[2], k, 'PX', d)
+redis.call('PEXPIRE', KEYS[3], d + tonumber(ARGV[3]))
+redis.call('DEL', KEYS[1])
+return d
+`)
+
+func (x *Redis) Escalate(ctx context.Context, counter string, locked string, lockouts string,
+	max int64, window time.Duration, durations []time.Duration, decay time.Duration) (_ time.Duration, err error) {
+	args := []interface{}{max, window.Milliseconds(), decay.Milliseconds()}
+	for _, v := range durations {
+		args = append(args, v.Milliseconds())
+	}
+	var d int64
+	if d, err = escalateScript.Run(ctx, x.RDb, []string{counter, locked, lockouts}, args...).Int64(); err != nil {
+		return
+	}
+	return milliseconds(d), nil
+}
+
+var compareAndDeleteScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+func (x *Redis) CompareAndDelete(ctx context.Context, key string, value string) (_ bool, err error) {
+	var n int64
+	if n, err = compareAndDeleteScript.Run(ctx, x.RDb, []string{key}, value).Int64(); err != nil {
+		return
+	}
+	return n != 0, nil
+}
+
+var peekScript = redis.NewScript(`
+return {redis.call('GET', KEYS[1]), redis.call('PTTL', KEYS[1])}
+`)
+
+// Peek reads the keys by a script each in a pipeline, they may live in different cluster slots.
+func (x *Redis) Peek(ctx context.Context, keys ...string) (entries []Entry, err error) {
+	var cmds []redis.Cmder
+	if cmds, err = x.RDb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, key := range keys {
+			peekScript.Eval(ctx, p, []string{key})
+		}
+		return nil
+	}); err != nil {
+		return
+	}
+	entries = make([]Entry, len(keys))
+	for i, cmd := range cmds {
+		var v []interface{}
+		if v, err = cmd.(*redis.Cmd).Slice(); err != nil {
+			return
+		}
+		if value, ok := v[0].(string); ok {
+			entries[i] = Entry{Exists: true, Value: value, TTL: milliseconds(v[1].(int64))}
+		}
+	}
+	return
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Store is the key value storage shared by captcha and locker, a ttl of 0 never expires.
+type Store interface {
+	Set(ctx context.Context, key string, value string, ttl time.Duration) error
+	// Get returns ErrNotExists when the key does not exist or is expired.
+	Get(ctx context.Context, key string) (string, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	// Del returns the number of deleted keys.
+	Del(ctx context.Context, keys ...string) (int64, error)
+	// Incr increments the counter atomically, ttl is set only when the counter is created or has none.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// IncrTTL is Incr returning the ttl of the counter too.
+	IncrTTL(ctx context.Context, key string, ttl time.Duration) (int64, time.Duration, error)
+	// Expire resets the ttl of the key, false when it does not exist.
+	Expire(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// TTL returns ErrNotExists when the key does not exist, and -1 without expiry.
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	// Attempt reads key and counts an attempt on counter in one step, the counter expires with key.
+	// The attempt max deletes both, returning the value for the last time.
+	// It returns ErrNotExists when key does not exist, they must share a cluster slot.
+	Attempt(ctx context.Context, key string, counter string, max int64) (string, int64, error)
+	// Escalate counts a failure on counter as IncrTTL with window, unless locked exists.
+	// The failure reaching max increments lockouts to k, sets locked to k for the k-th of durations,
+	// the last one repeats, expires lockouts after it and decay, and deletes counter, all in one step.
+	// It returns the ttl of locked, 0 when it is not locked, they must share a cluster slot.
+	Escalate(ctx context.Context, counter string, locked string, lockouts string,
+		max int64, window time.Duration, durations []time.Duration, decay time.Duration) (time.Duration, error)
+	// CompareAndDelete deletes the key atomically when it holds value.
+	CompareAndDelete(ctx context.Context, key string, value string) (bool, error)
+	// Peek reads the keys with their ttl in one round trip, each key atomically.
+	Peek(ctx context.Context, keys ...string) ([]Entry, error)
+}
+
+// Entry is a value read with its ttl by Peek.
+type Entry struct {
+	Exists bool
+	Value  string
+	// TTL is -1 without expiry.
+	TTL time.Duration
+}
+
+var (
+	ErrNotExists = errors.New("the key does not exists")
+)
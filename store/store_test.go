@@ -0,0 +1,301 @@
+package store_test
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/weplanx/go/store"
+	"log"
+	"os"
+	"testing"
+	"time"
+)
+
+var stores map[string]store.Store
+
+func TestMain(m *testing.M) {
+	opts, err := redis.ParseURL(os.Getenv("DATABASE_REDIS"))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	stores = map[string]store.Store{
+		"redis":  store.NewRedis(redis.NewClient(opts)),
+		"memory": store.NewMemory(),
+	}
+	os.Exit(m.Run())
+}
+
+func each(t *testing.T, fn func(t *testing.T, s store.Store)) {
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			fn(t, s)
+		})
+	}
+}
+
+func TestSetGet(t *testing.T) {
+	each(t, func(t *testing.T, s store.Store) {
+		ctx := context.TODO()
+		assert.NoError(t, s.Set(ctx, "store:dev", "abc", time.Second*60))
+		v, err := s.Get(ctx, "store:dev")
+		assert.NoError(t, err)
+		assert.Equal(t, "abc", v)
+		ok, err := s.Exists(ctx, "store:dev")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		_, err = s.Get(ctx, "store:unknow")
+		assert.ErrorIs(t, err, store.ErrNotExists)
+		ok, err = s.Exists(ctx, "store:unknow")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestExpire(t *testing.T) {
+	each(t, func(t *testing.T, s store.Store) {
+		ctx := context.TODO()
+		assert.NoError(t, s.Set(ctx, "store:short", "abc", time.Millisecond*100))
+		time.Sleep(time.Millisecond * 200)
+		_, err := s.Get(ctx, "store:short")
+		assert.ErrorIs(t, err, store.ErrNotExists)
+		_, err = s.TTL(ctx, "store:short")
+		assert.ErrorIs(t, err, store.ErrNotExists)
+	})
+}
+
+func TestTTL(t *testing.T) {
+	each(t, func(t *testing.T, s store.Store) {
+		ctx := context.TODO()
+		assert.NoError(t, s.Set(ctx, "store:ttl", "abc", time.Second*60))
+		ttl, err := s.TTL(ctx, "store:ttl")
+		assert.NoError(t, err)
+		assert.Greater(t, ttl, time.Second*59)
+		assert.LessOrEqual(t, ttl, time.Second*60)
+
+		assert.NoError(t, s.Set(ctx, "store:persist", "abc", 0))
+		ttl, err = s.TTL(ctx, "store:persist")
+		assert.NoError(t, err)
+		assert.Less(t, ttl, time.Duration(0))
+		_, err = s.Del(ctx, "store:persist")
+		assert.NoError(t, err)
+	})
+}
+
+func TestDel(t *testing.T) {
+	each(t, func(t *testing.T, s store.Store) {
+		ctx := context.TODO()
+		assert.NoError(t, s.Set(ctx, "store:a", "1", time.Second*60))
+		assert.NoError(t, s.Set(ctx, "store:b", "2", time.Second*60))
+		n, err := s.Del(ctx, "store:a", "store:b", "store:unknow")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+		ok, err := s.Exists(ctx, "store:a")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestIncr(t *testing.T) {
+	each(t, func(t *testing.T, s store.Store) {
+		ctx := context.TODO()
+		for i := 1; i <= 3; i++ {
+			n, err := s.Incr(ctx, "store:counter", time.Second*60)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(i), n)
+		}
+		ttl, err := s.TTL(ctx, "store:counter")
+		assert.NoError(t, err)
+		assert.Greater(t, ttl, time.Second*59)
+
+		assert.NoError(t, s.Set(ctx, "store:text", "abc", time.Second*60))
+		_, err = s.Incr(ctx, "store:text", time.Second*60)
+		assert.Error(t, err)
+
+		_, err = s.Del(ctx, "store:counter", "store:text")
+		assert.NoError(t, err)
+	})
+}
+
+func TestExpireKey(t *testing.T) {
+	each(t, func(t *testing.T, s store.Store) {
+		ctx := context.TODO()
+		assert.NoError(t, s.Set(ctx, "store:expire", "abc", time.Second*60))
+		ok, err := s.Expire(ctx, "store:expire", time.Second*120)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		ttl, err := s.TTL(ctx, "store:expire")
+		assert.NoError(t, err)
+		assert.Greater(t, ttl, time.Second*119)
+
+		ok, err = s.Expire(ctx, "store:expire", 0)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		ttl, err = s.TTL(ctx, "store:expire")
+		assert.NoError(t, err)
+		assert.Equal(t, time.Duration(-1), ttl)
+
+		ok, err = s.Expire(ctx, "store:unknow", time.Second)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		_, err = s.Del(ctx, "store:expire")
+		assert.NoError(t, err)
+	})
+}
+
+func TestIncrTTL(t *testing.T) {
+	each(t, func(t *testing.T, s store.Store) {
+		ctx := context.TODO()
+		n, ttl, err := s.IncrTTL(ctx, "store:incr_ttl", time.Second*60)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+		assert.Greater(t, ttl, time.Second*59)
+		n, ttl, err = s.IncrTTL(ctx, "store:incr_ttl", time.Second*120)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+		assert.LessOrEqual(t, ttl, time.Second*60)
+
+		// a counter left without expiry gets one
+		assert.NoError(t, s.Set(ctx, "store:incr_ttl", "5", 0))
+		n, ttl, err = s.IncrTTL(ctx, "store:incr_ttl", time.Second*60)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(6), n)
+		assert.Greater(t, ttl, time.Second*59)
+
+		n, ttl, err = s.IncrTTL(ctx, "store:incr_ttl:persist", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+		assert.Equal(t, time.Duration(-1), ttl)
+
+		_, err = s.Del(ctx, "store:incr_ttl", "store:incr_ttl:persist")
+		assert.NoError(t, err)
+	})
+}
+
+func TestPeek(t *testing.T) {
+	each(t, func(t *testing.T, s store.Store) {
+		ctx := context.TODO()
+		assert.NoError(t, s.Set(ctx, "store:peek:a", "abc", time.Second*60))
+		assert.NoError(t, s.Set(ctx, "store:peek:b", "xyz", 0))
+		entries, err := s.Peek(ctx, "store:peek:a", "store:unknow", "store:peek:b")
+		assert.NoError(t, err)
+		assert.Len(t, entries, 3)
+		assert.True(t, entries[0].Exists)
+		assert.Equal(t, "abc", entries[0].Value)
+		assert.Greater(t, entries[0].TTL, time.Second*59)
+		assert.Equal(t, store.Entry{}, entries[1])
+		assert.Equal(t, store.Entry{Exists: true, Value: "xyz", TTL: -1}, entries[2])
+
+		entries, err = s.Peek(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, entries)
+		_, err = s.Del(ctx, "store:peek:a", "store:peek:b")
+		assert.NoError(t, err)
+	})
+}
+
+func TestCompareAndDelete(t *testing.T) {
+	each(t, func(t *testing.T, s store.Store) {
+		ctx := context.TODO()
+		assert.NoError(t, s.Set(ctx, "store:cad", "abc", time.Second*60))
+		ok, err := s.CompareAndDelete(ctx, "store:cad", "xyz")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		ok, err = s.CompareAndDelete(ctx, "store:cad", "abc")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		ok, err = s.CompareAndDelete(ctx, "store:cad", "abc")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestAttempt(t *testing.T) {
+	each(t, func(t *testing.T, s store.Store) {
+		ctx := context.TODO()
+		assert.NoError(t, s.Set(ctx, "store:code", "1234", time.Second*60))
+		for i := int64(1); i <= 2; i++ {
+			v, n, err := s.Attempt(ctx, "store:code", "{store:code}:attempts", 3)
+			assert.NoError(t, err)
+			assert.Equal(t, "1234", v)
+			assert.Equal(t, i, n)
+		}
+		// the counter expires with the key
+		ttl, err := s.TTL(ctx, "{store:code}:attempts")
+		assert.NoError(t, err)
+		assert.Greater(t, ttl, time.Second*59)
+
+		v, n, err := s.Attempt(ctx, "store:code", "{store:code}:attempts", 3)
+		assert.NoError(t, err)
+		assert.Equal(t, "1234", v)
+		assert.Equal(t, int64(3), n)
+		ok, err := s.Exists(ctx, "store:code")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		ok, err = s.Exists(ctx, "{store:code}:attempts")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		// no orphan counter is left
+		_, _, err = s.Attempt(ctx, "store:code", "{store:code}:attempts", 3)
+		assert.ErrorIs(t, err, store.ErrNotExists)
+		ok, err = s.Exists(ctx, "{store:code}:attempts")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestEscalate(t *testing.T) {
+	each(t, func(t *testing.T, s store.Store) {
+		ctx := context.TODO()
+		keys := []string{"{store:esc}:failures", "{store:esc}:locked", "{store:esc}:lockouts"}
+		durations := []time.Duration{time.Minute, time.Hour}
+		escalate := func() (time.Duration, error) {
+			return s.Escalate(ctx, keys[0], keys[1], keys[2], 2, time.Minute, durations, time.Hour)
+		}
+		d, err := escalate()
+		assert.NoError(t, err)
+		assert.Equal(t, time.Duration(0), d)
+		ttl, err := s.TTL(ctx, keys[0])
+		assert.NoError(t, err)
+		assert.Greater(t, ttl, time.Second*59)
+
+		d, err = escalate()
+		assert.NoError(t, err)
+		assert.Equal(t, time.Minute, d)
+		v, err := s.Get(ctx, keys[1])
+		assert.NoError(t, err)
+		assert.Equal(t, "1", v)
+		// the lockouts decay after the lock, the failures count again after it
+		ttl, err = s.TTL(ctx, keys[2])
+		assert.NoError(t, err)
+		assert.Greater(t, ttl, time.Minute*60)
+		ok, err := s.Exists(ctx, keys[0])
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		// nothing is counted while locked
+		d, err = escalate()
+		assert.NoError(t, err)
+		assert.Greater(t, d, time.Second*59)
+		ok, err = s.Exists(ctx, keys[0])
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		for i := 0; i < 2; i++ {
+			_, err = s.Del(ctx, keys[1])
+			assert.NoError(t, err)
+			_, err = escalate()
+			assert.NoError(t, err)
+			d, err = escalate()
+			assert.NoError(t, err)
+			// the last one repeats
+			assert.Equal(t, time.Hour, d)
+		}
+		v, err = s.Get(ctx, keys[1])
+		assert.NoError(t, err)
+		assert.Equal(t, "3", v)
+		_, err = s.Del(ctx, keys...)
+		assert.NoError(t, err)
+	})
+}